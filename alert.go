@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+const AlertTimeout = 10 * time.Second
+
+// Alert is an event which must be notified to the user.
+type Alert struct {
+	Time    time.Time
+	Kind    string // "stale", "alive"...
+	Address string // Individual or group address which caused the alert
+	Name    string `json:",omitempty"` // Name of that address in the config file
+	Message string
+}
+
+func (a Alert) String() string {
+	return fmt.Sprintf("%s %s: %s", a.Time.Format("2006-01-02 15:04:05"), a.Kind, a.Message)
+}
+
+// AlertSink is something able to deliver alerts.
+type AlertSink interface {
+	Send(a Alert) error
+}
+
+// NewAlertSink creates an AlertSink from an "alert" line in the config file.
+func NewAlertSink(ac AlertConfig) (AlertSink, error) {
+	switch ac.Type {
+	case "log":
+		return logSink{}, nil
+	case "webhook":
+		if len(ac.Args) != 1 {
+			return nil, fmt.Errorf("alert webhook: syntax: alert webhook <url>")
+		}
+		return webhookSink{URL: ac.Args[0]}, nil
+	case "mqtt":
+		if len(ac.Args) != 2 {
+			return nil, fmt.Errorf("alert mqtt: syntax: alert mqtt <host:port> <topic>")
+		}
+		return mqttSink{Address: ac.Args[0], Topic: ac.Args[1]}, nil
+	case "smtp":
+		if len(ac.Args) != 3 {
+			return nil, fmt.Errorf("alert smtp: syntax: alert smtp <host:port> <from> <to>[,<to>...]")
+		}
+		return smtpSink{Address: ac.Args[0], From: ac.Args[1], To: strings.Split(ac.Args[2], ",")}, nil
+	default:
+		return nil, fmt.Errorf("unknown alert type %q", ac.Type)
+	}
+}
+
+// Alert sends a to every configured alert sink.
+func (s *Server) Alert(a Alert) {
+	for _, sink := range s.AlertSinks {
+		go func(sink AlertSink) {
+			if err := sink.Send(a); err != nil {
+				appLog.Errorf("Error sending alert %q: %v", a.Message, err)
+			}
+		}(sink)
+	}
+}
+
+type logSink struct{}
+
+func (logSink) Send(a Alert) error {
+	appLog.Warnf("ALERT: %s", a)
+	return nil
+}
+
+// webhookSink POSTs every alert in JSON to an URL.
+type webhookSink struct {
+	URL string
+}
+
+func (w webhookSink) Send(a Alert) error {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	client := http.Client{Timeout: AlertTimeout}
+	resp, err := client.Post(w.URL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s: %s", w.URL, resp.Status)
+	}
+	return nil
+}
+
+// mqttSink publishes every alert in JSON to a MQTT topic.
+// It uses MQTT 3.1.1, QoS 0 and a new connection for every alert.
+type mqttSink struct {
+	Address string
+	Topic   string
+}
+
+func (m mqttSink) Send(a Alert) error {
+	payload, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", m.Address, AlertTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(AlertTimeout))
+
+	// CONNECT: protocol "MQTT", level 4, clean session, keep alive 60s
+	var connect []byte
+	connect = append(connect, mqttString("MQTT")...)
+	connect = append(connect, 4, 0x02, 0, 60)
+	connect = append(connect, mqttString(fmt.Sprintf("knxweb-%d", time.Now().UnixNano()))...)
+	if _, err := conn.Write(mqttPacket(0x10, connect)); err != nil {
+		return err
+	}
+	var connack [4]byte
+	if _, err := io.ReadFull(conn, connack[:]); err != nil {
+		return err
+	}
+	if connack[0] != 0x20 || connack[3] != 0 {
+		return fmt.Errorf("mqtt %s: connection refused (code %d)", m.Address, connack[3])
+	}
+
+	// PUBLISH with QoS 0, then DISCONNECT
+	publish := append(mqttString(m.Topic), payload...)
+	if _, err := conn.Write(mqttPacket(0x30, publish)); err != nil {
+		return err
+	}
+	_, err = conn.Write(mqttPacket(0xE0, nil))
+	return err
+}
+
+// smtpSink sends every alert by e-mail, using a SMTP server without authentication.
+type smtpSink struct {
+	Address string
+	From    string
+	To      []string
+}
+
+func (m smtpSink) Send(a Alert) error {
+	subject := fmt.Sprintf("knxweb %s: %s", a.Kind, a.Message)
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\n\r\n%s\r\n",
+		m.From, strings.Join(m.To, ", "), subject, a.Time.Format(time.RFC1123Z), a)
+	return smtp.SendMail(m.Address, nil, m.From, m.To, []byte(msg))
+}
+
+func mqttString(s string) []byte {
+	return append([]byte{byte(len(s) >> 8), byte(len(s))}, s...)
+}
+
+func mqttPacket(header byte, body []byte) []byte {
+	p := []byte{header}
+	l := len(body)
+	for {
+		b := byte(l % 128)
+		l /= 128
+		if l > 0 {
+			b |= 0x80
+		}
+		p = append(p, b)
+		if l == 0 {
+			break
+		}
+	}
+	return append(p, body...)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// testMail is a message received by fakeSMTP.
+type testMail struct {
+	From string
+	To   []string
+	Data string
+}
+
+// fakeSMTP starts a SMTP server which accepts every message and sends it to the returned channel.
+func fakeSMTP(t *testing.T) (string, <-chan testMail) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	mails := make(chan testMail, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, mails)
+		}
+	}()
+	return l.Addr().String(), mails
+}
+
+func serveSMTP(conn net.Conn, mails chan<- testMail) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+	reply("220 localhost ESMTP")
+	var m testMail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			m = testMail{From: strings.Trim(line[len("MAIL FROM:"):], "<>")}
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			m.To = append(m.To, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			m.Data = data.String()
+			mails <- m
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func receiveMail(t *testing.T, mails <-chan testMail) testMail {
+	select {
+	case m := <-mails:
+		return m
+	case <-time.After(AlertTimeout):
+		t.Fatal("no mail received")
+	}
+	return testMail{}
+}
+
+func TestSMTPAlert(t *testing.T) {
+	addr, mails := fakeSMTP(t)
+	sink, err := NewAlertSink(AlertConfig{Type: "smtp", Args: []string{addr, "knxweb@example.com", "alice@example.com,bob@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	group := cemi.GroupAddr(0x0A01)
+	config.Store(&Config{Addresses: map[cemi.GroupAddr]addrNameType{group: {Name: "serverroom/temperature"}}})
+	s := &Server{AlertSinks: []AlertSink{sink}}
+	st := &ruleState{Rule: Rule{Name: "hot", Target: "serverroom/temperature", Addr: group, Op: ">", Value: 28}}
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	st.matching, st.since, st.value = true, now, 29.5
+	s.evalRule(st, now)
+	m := receiveMail(t, mails)
+	if m.From != "knxweb@example.com" || len(m.To) != 2 || m.To[0] != "alice@example.com" || m.To[1] != "bob@example.com" {
+		t.Errorf("alert: got from %q to %q", m.From, m.To)
+	}
+	for _, want := range []string{
+		"Subject: knxweb threshold: hot: serverroom/temperature > 28 (value 29.5)\r\n",
+		"To: alice@example.com, bob@example.com\r\n",
+		"Date: Wed, 01 Jul 2026 12:00:00 +0000\r\n",
+	} {
+		if !strings.Contains(m.Data, want) {
+			t.Errorf("alert: %q not found in message:\n%s", want, m.Data)
+		}
+	}
+
+	st.matching, st.value = false, 26
+	s.evalRule(st, now.Add(time.Minute))
+	m = receiveMail(t, mails)
+	if want := "Subject: knxweb recovered: hot: recovered (value 26)\r\n"; !strings.Contains(m.Data, want) {
+		t.Errorf("recovery: %q not found in message:\n%s", want, m.Data)
+	}
+	if want := "\r\n\r\n2026-07-01 12:01:00 recovered: hot: recovered (value 26)\r\n"; !strings.Contains(m.Data, want) {
+		t.Errorf("recovery: body %q not found in message:\n%s", want, m.Data)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogLevel is the severity of a message in the application log.
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l LogLevel) String() string {
+	if l < LevelDebug || l > LevelError {
+		return "unknown"
+	}
+	return levelNames[l]
+}
+
+// ParseLogLevel parses "debug", "info", "warn" or "error".
+func ParseLogLevel(str string) (LogLevel, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(str, name) {
+			return LogLevel(i), nil
+		}
+	}
+	if strings.EqualFold(str, "warning") {
+		return LevelWarn, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", str)
+}
+
+// AppLogger is the application log: messages about what knxweb is doing,
+// as opposed to the KNX traffic logs written by Server.Log.
+type AppLogger struct {
+	mutex    sync.Mutex
+	level    LogLevel
+	json     bool
+	journald bool // prefix lines with "<N>", as understood by systemd-journald
+	out      io.Writer
+	syslog   *syslog.Writer
+}
+
+var appLog = &AppLogger{level: LevelInfo, out: os.Stderr}
+
+// Setup configures the logger with the level, format ("text" or "json")
+// and output ("stderr", "stdout", "syslog", "journald" or a file name).
+func (l *AppLogger) Setup(level LogLevel, format, output string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.level = level
+	switch format {
+	case "", "text":
+		l.json = false
+	case "json":
+		l.json = true
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+	l.journald = false
+	l.syslog = nil
+	switch output {
+	case "", "stderr":
+		l.out = os.Stderr
+	case "stdout":
+		l.out = os.Stdout
+	case "journald":
+		l.out = os.Stderr
+		l.journald = true
+	case "syslog":
+		w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, "knxweb")
+		if err != nil {
+			return err
+		}
+		l.syslog = w
+	default:
+		f, err := os.OpenFile(output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+		if err != nil {
+			return err
+		}
+		l.out = f
+	}
+	return nil
+}
+
+// SetLevel changes the minimum level of the messages to log.
+func (l *AppLogger) SetLevel(level LogLevel) {
+	l.mutex.Lock()
+	l.level = level
+	l.mutex.Unlock()
+}
+
+func (l *AppLogger) logf(level LogLevel, format string, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if level < l.level {
+		return
+	}
+	msg := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+	now := time.Now()
+	var line string
+	if l.json {
+		b, _ := json.Marshal(struct {
+			Time    time.Time `json:"time"`
+			Level   string    `json:"level"`
+			Message string    `json:"msg"`
+		}{now, level.String(), msg})
+		line = string(b)
+	} else if l.syslog != nil || l.journald {
+		// syslog and journald add their own timestamps
+		line = level.String() + ": " + msg
+	} else {
+		line = now.Format("2006/01/02 15:04:05 ") + level.String() + ": " + msg
+	}
+
+	if l.syslog != nil {
+		switch level {
+		case LevelDebug:
+			l.syslog.Debug(line)
+		case LevelInfo:
+			l.syslog.Info(line)
+		case LevelWarn:
+			l.syslog.Warning(line)
+		default:
+			l.syslog.Err(line)
+		}
+		return
+	}
+	if l.journald {
+		// sd-daemon(3) priorities: 7=debug, 6=info, 4=warning, 3=err
+		line = fmt.Sprintf("<%d>%s", []int{7, 6, 4, 3}[level], line)
+	}
+	fmt.Fprintln(l.out, line)
+}
+
+func (l *AppLogger) Debugf(format string, args ...interface{}) { l.logf(LevelDebug, format, args...) }
+func (l *AppLogger) Infof(format string, args ...interface{})  { l.logf(LevelInfo, format, args...) }
+func (l *AppLogger) Warnf(format string, args ...interface{})  { l.logf(LevelWarn, format, args...) }
+func (l *AppLogger) Errorf(format string, args ...interface{}) { l.logf(LevelError, format, args...) }
+
+// Fatalf logs an error and exits.
+func (l *AppLogger) Fatalf(format string, args ...interface{}) {
+	l.logf(LevelError, format, args...)
+	os.Exit(1)
+}
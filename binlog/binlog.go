@@ -0,0 +1,205 @@
+// Package binlog reads and writes compact binary logs of KNX telegrams.
+//
+// A log file starts with an 8-byte header: the magic string "KNXLOG",
+// the format version (currently 1) and a reserved zero byte.
+//
+// It is followed by a sequence of records, each one with this layout
+// (all integers are big-endian):
+//
+//	type     1 byte   'G' (gateway) or 'T' (telegram)
+//	length   2 bytes  length of the body
+//	body     length bytes
+//	checksum 4 bytes  CRC-32 (IEEE) of type, length and body
+//
+// A gateway record assigns a 1-byte id to a gateway name, and must appear
+// in a file before any telegram coming from that gateway:
+//
+//	id       1 byte
+//	name     rest of the body
+//
+// A telegram record contains:
+//
+//	time        8 bytes  milliseconds since the Unix epoch
+//	gateway     1 byte   gateway id
+//	command     1 byte   0=read, 1=response, 2=write
+//	source      2 bytes  individual address
+//	destination 2 bytes  group address
+//	data        rest of the body
+package binlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+const (
+	Magic   = "KNXLOG"
+	Version = 1
+
+	recordGateway  = 'G'
+	recordTelegram = 'T'
+)
+
+var (
+	ErrFormat   = errors.New("binlog: not a KNX binary log")
+	ErrVersion  = errors.New("binlog: unsupported version")
+	ErrChecksum = errors.New("binlog: checksum mismatch")
+)
+
+// Telegram is a KNX telegram as stored in a log.
+type Telegram struct {
+	Time    time.Time
+	Gateway string
+	Event   knx.GroupEvent
+}
+
+// Writer writes telegrams to a binary log.
+type Writer struct {
+	w        io.Writer
+	gateways map[string]byte
+}
+
+// NewWriter writes the file header to w and returns a Writer.
+func NewWriter(w io.Writer) (*Writer, error) {
+	header := append([]byte(Magic), Version, 0)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, gateways: make(map[string]byte)}, nil
+}
+
+// Write appends a telegram to the log, and returns the number of bytes written.
+func (w *Writer) Write(t Telegram) (int, error) {
+	total := 0
+	id, ok := w.gateways[t.Gateway]
+	if !ok {
+		if len(w.gateways) > 255 {
+			return 0, fmt.Errorf("binlog: too many gateways")
+		}
+		id = byte(len(w.gateways))
+		n, err := w.record(recordGateway, append([]byte{id}, t.Gateway...))
+		total += n
+		if err != nil {
+			return total, err
+		}
+		w.gateways[t.Gateway] = id
+	}
+	body := make([]byte, 14, 14+len(t.Event.Data))
+	binary.BigEndian.PutUint64(body[0:8], uint64(t.Time.UnixNano()/int64(time.Millisecond)))
+	body[8] = id
+	body[9] = byte(t.Event.Command)
+	binary.BigEndian.PutUint16(body[10:12], uint16(t.Event.Source))
+	binary.BigEndian.PutUint16(body[12:14], uint16(t.Event.Destination))
+	body = append(body, t.Event.Data...)
+	n, err := w.record(recordTelegram, body)
+	return total + n, err
+}
+
+func (w *Writer) record(typ byte, body []byte) (int, error) {
+	if len(body) > 0xFFFF {
+		return 0, fmt.Errorf("binlog: record too long")
+	}
+	buf := make([]byte, 3, 3+len(body)+4)
+	buf[0] = typ
+	binary.BigEndian.PutUint16(buf[1:3], uint16(len(body)))
+	buf = append(buf, body...)
+	buf = append(buf, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(buf[len(buf)-4:], crc32.ChecksumIEEE(buf[:len(buf)-4]))
+	return w.w.Write(buf)
+}
+
+// Reader reads telegrams from a binary log.
+type Reader struct {
+	r        *bufio.Reader
+	gateways map[byte]string
+}
+
+// NewReader checks the file header in r and returns a Reader.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(Magic)+2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrFormat
+		}
+		return nil, err
+	}
+	if string(header[:len(Magic)]) != Magic {
+		return nil, ErrFormat
+	}
+	if header[len(Magic)] != Version {
+		return nil, ErrVersion
+	}
+	return &Reader{r: br, gateways: make(map[byte]string)}, nil
+}
+
+// Next returns the next telegram in the log, or io.EOF at the end of it.
+func (r *Reader) Next() (Telegram, error) {
+	for {
+		typ, body, err := r.record()
+		if err != nil {
+			return Telegram{}, err
+		}
+		switch typ {
+		case recordGateway:
+			if len(body) < 1 {
+				return Telegram{}, ErrFormat
+			}
+			r.gateways[body[0]] = string(body[1:])
+		case recordTelegram:
+			if len(body) < 14 {
+				return Telegram{}, ErrFormat
+			}
+			ms := int64(binary.BigEndian.Uint64(body[0:8]))
+			gw, ok := r.gateways[body[8]]
+			if !ok {
+				return Telegram{}, fmt.Errorf("binlog: unknown gateway id %d", body[8])
+			}
+			return Telegram{
+				Time:    time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)),
+				Gateway: gw,
+				Event: knx.GroupEvent{
+					Command:     knx.GroupCommand(body[9]),
+					Source:      cemi.IndividualAddr(binary.BigEndian.Uint16(body[10:12])),
+					Destination: cemi.GroupAddr(binary.BigEndian.Uint16(body[12:14])),
+					Data:        append([]byte(nil), body[14:]...),
+				},
+			}, nil
+		default:
+			// unknown record types are skipped, to allow future extensions
+		}
+	}
+}
+
+func (r *Reader) record() (byte, []byte, error) {
+	var head [3]byte
+	if _, err := io.ReadFull(r.r, head[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, nil, ErrFormat
+		}
+		return 0, nil, err
+	}
+	l := int(binary.BigEndian.Uint16(head[1:3]))
+	buf := make([]byte, l+4)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return 0, nil, ErrFormat
+		}
+		return 0, nil, err
+	}
+	crc := crc32.NewIEEE()
+	crc.Write(head[:])
+	crc.Write(buf[:l])
+	if crc.Sum32() != binary.BigEndian.Uint32(buf[l:]) {
+		return 0, nil, ErrChecksum
+	}
+	return head[0], buf[:l], nil
+}
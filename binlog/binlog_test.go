@@ -0,0 +1,100 @@
+package binlog
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+var testTelegrams = []Telegram{
+	{
+		Time:    time.Date(2026, 1, 2, 3, 4, 5, 6e6, time.UTC),
+		Gateway: "192.168.1.11:3671",
+		Event:   knx.GroupEvent{Command: knx.GroupWrite, Source: cemi.IndividualAddr(0x110a), Destination: cemi.GroupAddr(0x1507), Data: []byte{0x0c, 0x1a}},
+	},
+	{
+		Time:    time.Date(2026, 1, 2, 3, 4, 6, 0, time.UTC),
+		Gateway: "192.168.1.12:3671",
+		Event:   knx.GroupEvent{Command: knx.GroupRead, Source: cemi.IndividualAddr(0x1101), Destination: cemi.GroupAddr(0x0801), Data: []byte{}},
+	},
+	{
+		Time:    time.Date(2026, 1, 2, 3, 4, 7, 123e6, time.UTC),
+		Gateway: "192.168.1.11:3671",
+		Event:   knx.GroupEvent{Command: knx.GroupResponse, Source: cemi.IndividualAddr(0x110a), Destination: cemi.GroupAddr(0x1507), Data: []byte{0x01}},
+	},
+}
+
+func writeTestLog(t *testing.T) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tg := range testTelegrams {
+		if _, err := w.Write(tg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(writeTestLog(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range testTelegrams {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("telegram %d: %v", i, err)
+		}
+		if !got.Time.Equal(want.Time) || got.Gateway != want.Gateway ||
+			got.Event.Command != want.Event.Command || got.Event.Source != want.Event.Source ||
+			got.Event.Destination != want.Event.Destination || !bytes.Equal(got.Event.Data, want.Event.Data) {
+			t.Errorf("telegram %d: got %+v, want %+v", i, got, want)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("after the last telegram: got %v, want io.EOF", err)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	b := writeTestLog(t)
+	// corrupt the data of the last telegram, before its checksum
+	b[len(b)-5] ^= 0xFF
+	r, err := NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []error
+	for {
+		_, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+	}
+	if !reflect.DeepEqual(errs, []error{ErrChecksum}) {
+		t.Errorf("got errors %v, want %v", errs, ErrChecksum)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader([]byte("NOTALOG!"))); err != ErrFormat {
+		t.Errorf("bad magic: got %v, want %v", err, ErrFormat)
+	}
+	b := writeTestLog(t)
+	b[len(Magic)] = Version + 1
+	if _, err := NewReader(bytes.NewReader(b)); err != ErrVersion {
+		t.Errorf("bad version: got %v, want %v", err, ErrVersion)
+	}
+}
@@ -1,170 +1,351 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"io"
-	"log"
 	"os"
+	"sort"
+	"strings"
 
-	"github.com/cespedes/knxweb/ets"
+	"github.com/cespedes/knxweb/knxproj"
+	"github.com/vapourismo/knx-go/knx/cemi"
 )
 
+// options are the flags common to all the commands.
+type options struct {
+	format       string
+	password     string
+	passwordFile string
+
+	area  int    // only devices in this area (-1: all)
+	line  string // only devices in this line, as in "1.1"
+	main  int    // only group addresses in this main group (-1: all)
+	match string // only names containing this, ignoring case
+	dpt   string // only datapoint types starting with this, as in "9." or "9.001"
+}
+
+var commands = map[string]func(opts options, args []string) (*table, error){
+	"devices":    devicesCommand,
+	"groups":     groupsCommand,
+	"objects":    objectsCommand,
+	"links":      linksCommand,
+	"datapoints": datapointsCommand,
+	"diff":       diffCommand,
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [options] <file.knxproj>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s diff [options] <old.knxproj> <new.knxproj>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s dump [options] <file.knxproj>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Commands: devices, groups, objects, links, datapoints, diff, dump\n")
+	fmt.Fprintf(os.Stderr, "Run \"%s <command> -h\" for the options.\n", os.Args[0])
+}
+
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <file.knxproj>\n", os.Args[0])
-		os.Exit(1)
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
 	}
-	archive, err := ets.OpenExportArchive(os.Args[1])
+	cmd := os.Args[1]
+	fn, ok := commands[cmd]
+	if !ok && cmd != "dump" {
+		usage()
+		os.Exit(2)
+	}
+
+	var opts options
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	fs.StringVar(&opts.format, "format", "table", "output format: table, json or csv")
+	fs.StringVar(&opts.password, "password", "", "password of the project, if protected (default: $"+knxproj.PasswordEnv+")")
+	fs.StringVar(&opts.passwordFile, "password-file", "", "file with the password of the project")
+	fs.IntVar(&opts.area, "area", -1, "show only devices in this area")
+	fs.StringVar(&opts.line, "line", "", "show only devices in this line (as in 1.1)")
+	fs.IntVar(&opts.main, "main", -1, "show only group addresses in this main group")
+	fs.StringVar(&opts.match, "match", "", "show only names containing this text")
+	fs.StringVar(&opts.dpt, "dpt", "", "show only datapoint types starting with this (as in 9. or 9.001)")
+	fs.Parse(os.Args[2:])
+	switch opts.format {
+	case "table", "json", "csv":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", opts.format)
+		os.Exit(2)
+	}
+	nargs := 1
+	if cmd == "diff" {
+		nargs = 2
+	}
+	if fs.NArg() != nargs {
+		fs.Usage()
+		os.Exit(2)
+	}
+	pw, err := knxproj.Password(opts.password, opts.passwordFile)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	opts.password = pw
+
+	if cmd == "dump" {
+		archive, err := knxproj.OpenArchive(fs.Arg(0), opts.password)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer archive.Close()
+		dump(archive)
 		return
 	}
 
-	// Make sure to close the archive eventually.
-	defer archive.Close()
+	t, err := fn(opts, fs.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := t.write(os.Stdout, opts.format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
+
+func open(opts options, filename string) (*knxproj.Project, error) {
+	p, err := knxproj.Open(filename, opts.password)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return p, nil
+}
 
-	for n, manuFile := range archive.ManufacturerFiles {
-		fmt.Printf("ManufacturerFile %d/%d: id=%q content=%q\n", n+1, len(archive.ManufacturerFiles), manuFile.ManufacturerID, manuFile.ContentID)
-		manuData, err := manuFile.Decode()
-		if err != nil {
-			fmt.Printf("  error: %v\n", err)
+func (o options) device(d knxproj.Device) bool {
+	return o.place(d) && o.name(d.Name)
+}
+
+// place reports whether a device is in the area and line given.
+func (o options) place(d knxproj.Device) bool {
+	if o.area >= 0 && int(d.Address>>12) != o.area {
+		return false
+	}
+	return o.line == "" || fmt.Sprintf("%d.%d", d.Address>>12, (d.Address>>8)&0xF) == o.line
+}
+
+func (o options) group(g knxproj.Group) bool {
+	return o.mainGroup(g.Address) && o.name(g.Name) && o.datapoint(g.DPT)
+}
+
+func (o options) mainGroup(addr cemi.GroupAddr) bool {
+	return o.main < 0 || int(addr>>11) == o.main
+}
+
+func (o options) name(name string) bool {
+	return o.match == "" || strings.Contains(strings.ToLower(name), strings.ToLower(o.match))
+}
+
+func (o options) datapoint(dpt string) bool {
+	return strings.HasPrefix(dpt, o.dpt)
+}
+
+func devicesCommand(opts options, args []string) (*table, error) {
+	p, err := open(opts, args[0])
+	if err != nil {
+		return nil, err
+	}
+	t := &table{Header: []string{"Address", "Name", "Area", "Line", "Location", "Objects"}}
+	devices := []knxproj.Device{} // "[]" in JSON if there are none
+	for _, d := range p.Devices {
+		if !opts.device(d) {
 			continue
 		}
-		fmt.Printf("  Manufacturer=%q\n", manuData.Manufacturer)
-		for i, prog := range manuData.Programs {
-			fmt.Printf("  Program %d/%d:\n", i+1, len(manuData.Programs))
-			fmt.Printf("    ID=%q\n", prog.ID)
-			fmt.Printf("    Name=%q\n", prog.Name)
-			fmt.Printf("    Version=%d\n", prog.Version)
-			for j, obj := range prog.Objects {
-				fmt.Printf("    Object %d/%d:\n", j+1, len(prog.Objects))
-				fmt.Printf("      ID=%q\n", obj.ID)
-				fmt.Printf("      Name=%q\n", obj.Name)
-				fmt.Printf("      Text=%q\n", obj.Text)
-				fmt.Printf("      Description=%q\n", obj.Description)
-				fmt.Printf("      FunctionText=%q\n", obj.FunctionText)
-				fmt.Printf("      ObjectSize=%q\n", obj.ObjectSize)
-				fmt.Printf("      DatapointType=%q\n", obj.DatapointType)
-				fmt.Printf("      Priority=%q\n", obj.Priority)
-				fmt.Printf("      R=%v W=%v C=%v T=%v U=%v RoI=%v\n", obj.ReadFlag, obj.WriteFlag, obj.CommunicationFlag, obj.TransmitFlag, obj.UpdateFlag, obj.ReadOnInitFlag)
-			}
-			for j, or := range prog.ObjectRefs {
-				fmt.Printf("    ObjectRef %d/%d:\n", j+1, len(prog.ObjectRefs))
-				fmt.Printf("      ID=%q\n", or.ID)
-				fmt.Printf("      RefID=%q\n", or.RefID)
-				if or.Name != nil {
-					fmt.Printf("      Name=%v\n", *or.Name)
-				}
-				if or.Text != nil {
-					fmt.Printf("      Text=%v\n", *or.Text)
-				}
-				if or.Description != nil {
-					fmt.Printf("      Description=%v\n", *or.Description)
-				}
-				if or.FunctionText != nil {
-					fmt.Printf("      FunctionText=%v\n", *or.FunctionText)
-				}
-				if or.ObjectSize != nil {
-					fmt.Printf("      ObjectSize=%v\n", *or.ObjectSize)
-				}
-				if or.DatapointType != nil {
-					fmt.Printf("      DatapointType=%v\n", *or.DatapointType)
-				}
-				if or.Priority != nil {
-					fmt.Printf("      Priority=%v\n", *or.Priority)
-				}
-				if or.ReadFlag != nil || or.WriteFlag != nil || or.CommunicationFlag != nil || or.TransmitFlag != nil || or.UpdateFlag != nil || or.ReadOnInitFlag != nil {
-					fmt.Printf("      ")
-					if or.ReadFlag != nil {
-						fmt.Printf("R=%v ", *or.ReadFlag)
-					}
-					if or.WriteFlag != nil {
-						fmt.Printf("W=%v ", *or.WriteFlag)
-					}
-					if or.CommunicationFlag != nil {
-						fmt.Printf("C=%v ", *or.CommunicationFlag)
-					}
-					if or.TransmitFlag != nil {
-						fmt.Printf("T=%v ", *or.TransmitFlag)
-					}
-					if or.UpdateFlag != nil {
-						fmt.Printf("U=%v ", *or.UpdateFlag)
-					}
-					if or.ReadOnInitFlag != nil {
-						fmt.Printf("RoI=%v ", *or.ReadOnInitFlag)
-					}
-					fmt.Println()
-				}
-			}
+		devices = append(devices, d)
+		t.add(d.Address.String(), d.Name, d.Area, d.Line, strings.Join(d.Location, "/"), fmt.Sprint(len(d.Objects)))
+	}
+	t.Values = devices
+	return t, nil
+}
+
+func groupsCommand(opts options, args []string) (*table, error) {
+	p, err := open(opts, args[0])
+	if err != nil {
+		return nil, err
+	}
+	t := &table{Header: []string{"Address", "Name", "Ranges", "DPT", "Description", "Devices"}}
+	groups := []knxproj.Group{}
+	for _, g := range p.Groups {
+		if !opts.group(g) {
+			continue
 		}
+		groups = append(groups, g)
+		t.add(g.Address.String(), g.Name, strings.Join(g.Ranges, "/"), g.DPT, g.Description, fmt.Sprint(len(g.Devices)))
 	}
+	t.Values = groups
+	return t, nil
+}
 
-	for n, projFile := range archive.ProjectFiles {
-		fmt.Printf("ProjectFile %d/%d: id=%q\n", n+1, len(archive.ProjectFiles), projFile.ProjectID)
-		for i, insFile := range projFile.InstallationFiles {
-			fmt.Printf("  InstallationFile %d/%d:\n", i+1, len(projFile.InstallationFiles))
-			fmt.Printf("    ID=%q\n", insFile.InstallationID)
-			proj, err := insFile.Decode()
-			if err != nil {
-				fmt.Printf("    Error: %v\n", err)
+// objectRow is a group object in the output of "objects".
+type objectRow struct {
+	Device     string
+	DeviceName string
+	Object     knxproj.Object
+}
+
+func objectsCommand(opts options, args []string) (*table, error) {
+	p, err := open(opts, args[0])
+	if err != nil {
+		return nil, err
+	}
+	t := &table{Header: []string{"Device", "DeviceName", "Object", "DPT", "Flags", "Groups"}}
+	objects := []objectRow{}
+	for _, d := range p.Devices {
+		if !opts.place(d) {
+			continue
+		}
+		for _, o := range d.Objects {
+			if !opts.datapoint(o.DPT) || !(opts.name(d.Name) || opts.name(o.Name)) {
 				continue
 			}
-			fmt.Printf("    ProjectID=%q\n", proj.ID)
-			for j, ins := range proj.Installations {
-				fmt.Printf("    Installation %d/%d:\n", j+1, len(proj.Installations))
-				fmt.Printf("      Name=%q\n", ins.Name)
-				for k, area := range ins.Topology {
-					fmt.Printf("      Area %d/%d:\n", k+1, len(ins.Topology))
-					fmt.Printf("        ID=%q\n", area.ID)
-					fmt.Printf("        Name=%q\n", area.Name)
-					fmt.Printf("        Address=%v\n", area.Address)
-					for l, line := range area.Lines {
-						fmt.Printf("        Line %d/%d:\n", l+1, len(area.Lines))
-						fmt.Printf("          ID=%q\n", line.ID)
-						fmt.Printf("          Name=%q\n", line.Name)
-						fmt.Printf("          Address=%v\n", line.Address)
-						for m, device := range line.Devices {
-							fmt.Printf("          Device %d/%d:\n", m+1, len(line.Devices))
-							fmt.Printf("            ID=%q\n", device.ID)
-							fmt.Printf("            Name=%q\n", device.Name)
-							fmt.Printf("            Address=%v\n", device.Address)
-							fmt.Printf("            ComObjects=%v\n", device.ComObjects)
-						}
-					}
-				}
-				fmt.Printf("      GroupAddresses: %v\n", ins.GroupAddresses)
+			if opts.main >= 0 && !linkedTo(o, opts.mainGroup) {
+				continue
 			}
+			objects = append(objects, objectRow{Device: d.Address.String(), DeviceName: d.Name, Object: o})
+			t.add(d.Address.String(), d.Name, o.Name, o.DPT, o.Flags, joinGroups(o.Groups))
 		}
+	}
+	t.Values = objects
+	return t, nil
+}
 
-		projInfo, err := projFile.Decode()
-		fmt.Printf("%+v\n", projInfo)
-		if err != nil {
-			log.Println(err)
+// link is a group object linked to a group address.
+type link struct {
+	addr      cemi.GroupAddr
+	Group     string
+	GroupName string
+	Device    string
+	Object    string
+	DPT       string
+	Flags     string
+}
+
+func linksCommand(opts options, args []string) (*table, error) {
+	p, err := open(opts, args[0])
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[cemi.GroupAddr]knxproj.Group)
+	for _, g := range p.Groups {
+		groups[g.Address] = g
+	}
+	links := []link{}
+	for _, d := range p.Devices {
+		if !opts.place(d) {
 			continue
 		}
-
-		// Variable projInfo contains the project info described in the projFile.
-		fmt.Println("  Project", projInfo.Name)
-
-		for _, instFile := range projFile.InstallationFiles {
-			proj, err := instFile.Decode()
-			if err != nil {
-				log.Println(err)
+		for _, o := range d.Objects {
+			if !opts.datapoint(o.DPT) {
 				continue
 			}
-
-			for _, inst := range proj.Installations {
-				fmt.Println("  Installation", inst.Name)
+			for _, addr := range o.Groups {
+				g := groups[addr]
+				if !opts.mainGroup(addr) || !(opts.name(g.Name) || opts.name(o.Name) || opts.name(d.Name)) {
+					continue
+				}
+				links = append(links, link{
+					addr:      addr,
+					Group:     addr.String(),
+					GroupName: g.Name,
+					Device:    d.Address.String(),
+					Object:    o.Name,
+					DPT:       o.DPT,
+					Flags:     o.Flags,
+				})
 			}
 		}
-		rc, err := projFile.File.Open()
-		if err != nil {
-			log.Fatal(err)
+	}
+	sort.SliceStable(links, func(i, j int) bool { return links[i].addr < links[j].addr })
+	t := &table{Header: []string{"Group", "GroupName", "Device", "Object", "DPT", "Flags"}, Values: links}
+	for _, l := range links {
+		t.add(l.Group, l.GroupName, l.Device, l.Object, l.DPT, l.Flags)
+	}
+	return t, nil
+}
+
+// datapoint is the usage of a datapoint type in a project.
+type datapoint struct {
+	DPT     string
+	Groups  int
+	Objects int
+}
+
+func datapointsCommand(opts options, args []string) (*table, error) {
+	p, err := open(opts, args[0])
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]*datapoint)
+	get := func(dpt string) *datapoint {
+		if counts[dpt] == nil {
+			counts[dpt] = &datapoint{DPT: dpt}
 		}
-		_, err = io.CopyN(os.Stdout, rc, 1000)
-		if err != nil {
-			log.Fatal(err)
+		return counts[dpt]
+	}
+	for _, g := range p.Groups {
+		if opts.group(g) {
+			get(g.DPT).Groups++
+		}
+	}
+	for _, d := range p.Devices {
+		if !opts.device(d) {
+			continue
+		}
+		for _, o := range d.Objects {
+			if opts.datapoint(o.DPT) {
+				get(o.DPT).Objects++
+			}
+		}
+	}
+	dps := []datapoint{}
+	for _, dp := range counts {
+		dps = append(dps, *dp)
+	}
+	sort.Slice(dps, func(i, j int) bool { return dps[i].DPT < dps[j].DPT })
+	t := &table{Header: []string{"DPT", "Groups", "Objects"}, Values: dps}
+	for _, dp := range dps {
+		t.add(dp.DPT, fmt.Sprint(dp.Groups), fmt.Sprint(dp.Objects))
+	}
+	return t, nil
+}
+
+func diffCommand(opts options, args []string) (*table, error) {
+	old, err := open(opts, args[0])
+	if err != nil {
+		return nil, err
+	}
+	new, err := open(opts, args[1])
+	if err != nil {
+		return nil, err
+	}
+	changes := knxproj.Diff(old, new)
+	if changes == nil {
+		changes = []knxproj.Change{}
+	}
+	t := &table{Header: []string{"Kind", "What", "Address", "Object", "Old", "New"}, Values: changes}
+	for _, c := range changes {
+		t.add(c.Kind, c.What, c.Address, c.Object, c.Old, c.New)
+	}
+	return t, nil
+}
+
+// linkedTo reports whether a group object is linked to any group address for which f is true.
+func linkedTo(o knxproj.Object, f func(cemi.GroupAddr) bool) bool {
+	for _, addr := range o.Groups {
+		if f(addr) {
+			return true
 		}
-		rc.Close()
 	}
+	return false
+}
+
+func joinGroups(groups []cemi.GroupAddr) string {
+	var s []string
+	for _, g := range groups {
+		s = append(s, g.String())
+	}
+	return strings.Join(s, " ")
 }
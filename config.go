@@ -2,17 +2,38 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/cespedes/knxweb/knxproj"
 	"github.com/vapourismo/knx-go/knx/cemi"
 )
 
-/* Syntax of KNXweb config file:
+/* Syntax of KNXweb config file (see readTOML for the TOML format):
 
+include conf.d/*.cfg
 logdir /var/log/knx
+loglevel info
+logformat text
+logoutput journald
+binlog yes
+jsonlog yes
+logmaxsize 64M
+logcompress yes
+logretention 365
+history 7
+etsproject /etc/knxweb/house.knxproj
+etspasswordfile /etc/knxweb/house.password
+room house/first/kitchen 1.1.10 2/5/7 kitchen/light
+snapshot /var/lib/knxweb/status.json.gz
+snapshotinterval 30s
+snapshotcompress yes
+snapshotkeep 3
 port 8001
 gateway 192.168.1.11 1/ 2/5/
 	...
@@ -20,23 +41,100 @@ device 1.1.10 myroom.thermostat
 	...
 address 2/5/7 9.001 myroom/temperature
 	...
+expect 1.1.10 1h
+expect 2/5/7 15m
+	...
+alert log
+alert webhook http://example.com/knx-alert
+alert mqtt 192.168.1.2:1883 knx/alerts
+alert smtp localhost:25 knxweb@example.com admin@example.com
+	...
+rule hot serverroom/temperature > 28 for 5m hysteresis 1 renotify 1h
+rule leak 3/1/4 = true
+	...
 */
+
 type addrNameType struct {
-	Name string
-	DPT  string
+	Name        string
+	DPT         string
+	Description string   `json:",omitempty"`
+	Unit        string   `json:",omitempty"` // overrides the unit of the DPT
+	Room        string   `json:",omitempty"`
+	Flags       []string `json:",omitempty"` // "readonly", "hidden"
+	Pos         string   `json:"-"`          // file and line where it is defined
 }
 
 type Gateway struct {
 	Address string
-	Groups  []string
+	Groups  []string // prefixes of the group addresses written through this gateway, as in "1/"
+	Lines   []string // lines of the devices expected on this gateway, as in "1.1"; empty: any
+	Pos     string   `json:"-"` // file and line where it is defined
+}
+
+// Expectation is a device or group address which should send something at least every Interval.
+type Expectation struct {
+	IsGroup  bool
+	Device   cemi.IndividualAddr
+	Group    cemi.GroupAddr
+	Interval time.Duration
+}
+
+func (e Expectation) String() string {
+	if e.IsGroup {
+		return e.Group.String()
+	}
+	return e.Device.String()
+}
+
+// Name returns the name of the device or group address in the config file, if any.
+func (e Expectation) Name(cfg *Config) string {
+	if e.IsGroup {
+		return cfg.Addresses[e.Group].Name
+	}
+	return cfg.Devices[e.Device]
+}
+
+// AlertConfig is an "alert" line in the config file: where to send alerts to.
+type AlertConfig struct {
+	Type string
+	Args []string
 }
 
 type Config struct {
-	Logdir    string                          // Where to store packet logs
+	Logdir    string   // Where to store packet logs
+	LogLevel  LogLevel // Minimum level of messages in the application log
+	LogFormat string   // Format of the application log: "text" or "json"
+	LogOutput string   // Where to write the application log: "stderr", "stdout", "syslog", "journald" or a file
+	Binlog    bool     // Whether to store packets in binary logs too
+	History   int      // Number of days of logs to load at startup
+
+	JSONLog      bool  // Whether to store packets in JSON Lines logs too
+	LogMaxSize   int64 // Maximum size of a JSON log file before rotating it (0: no limit)
+	LogCompress  bool  // Whether to compress JSON log files when closed
+	LogRetention int   // Number of days to keep JSON log files (0: forever)
+
+	Snapshot         string        // File to save the last values of every group address to
+	SnapshotInterval time.Duration // How often to save it
+	SnapshotCompress bool          // Whether to compress it
+	SnapshotKeep     int           // Number of previous snapshots to keep
+
 	Port      int                             // TCP port to listen HTTP requests
 	Gateways  []Gateway                       // List of KNX-IP gateways to connect to
 	Devices   map[cemi.IndividualAddr]string  // List of KNX devices
 	Addresses map[cemi.GroupAddr]addrNameType // List of KNX group addresses
+	Expects   []Expectation                   // Devices and group addresses which must send something periodically
+	Alerts    []AlertConfig                   // Where to send alerts
+	Rules     []Rule                          // Alerts on values of group addresses
+
+	DeviceRooms map[cemi.IndividualAddr]string // Room where every device is, if known
+
+	ETSProject      string           // ETS project to read devices and group addresses from
+	ETSPasswordFile string           // File with the password of the ETS project, if protected
+	ETS             *knxproj.Project // the ETS project, once read
+
+	devicePos map[cemi.IndividualAddr]string // file and line where every device is defined
+	including []string                       // files being read, to detect include cycles
+	roomRefs  []roomRef                      // "room" directives, resolved once everything is read
 }
 
 type UnknownDPT []byte
@@ -60,14 +158,125 @@ func (d UnknownDPT) Unit() string {
 	return ""
 }
 
+// ConfigErrors is the list of errors found while reading a config file.
+type ConfigErrors []error
+
+func (e ConfigErrors) Error() string {
+	var msgs []string
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+var errSyntax = errors.New("syntax error")
+
+// ReadConfig reads a config file, in the legacy line-based format
+// or in TOML (if its name ends in ".toml"), and the files it includes.
+// All the errors found are returned as a ConfigErrors.
 func ReadConfig(filename string) (*Config, error) {
 	var c Config
+	c.LogLevel = LevelInfo
+	c.Snapshot = DefaultSnapshotFile
+	c.SnapshotInterval = DefaultSnapshotInterval
 	c.Devices = make(map[cemi.IndividualAddr]string)
 	c.Addresses = make(map[cemi.GroupAddr]addrNameType)
+	c.devicePos = make(map[cemi.IndividualAddr]string)
+	c.DeviceRooms = make(map[cemi.IndividualAddr]string)
+
+	var errs ConfigErrors
+	err := c.readFile(filename)
+	if e, ok := err.(ConfigErrors); ok {
+		errs = append(errs, e...)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if c.ETSProject != "" && len(errs) == 0 {
+		if err := c.loadETS(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, ref := range c.roomRefs {
+		if err := c.setRoom(ref); err != nil {
+			errs = append(errs, fmt.Errorf("%s: room %s: %w", ref.Pos, ref.Room, err))
+		}
+	}
+
+	// rules can refer to names of addresses defined later, or in the ETS project
+	for i, r := range c.Rules {
+		addr, err := c.groupAddr(r.Target)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: rule %s: %w", r.Pos, r.Name, err))
+			continue
+		}
+		c.Rules[i].Addr = addr
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &c, nil
+}
+
+// readFile reads a config file in the format given by its name.
+func (c *Config) readFile(filename string) error {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	for i, f := range c.including {
+		if f == abs {
+			return fmt.Errorf("include cycle: %s -> %s", strings.Join(c.including[i:], " -> "), abs)
+		}
+	}
+	c.including = append(c.including, abs)
+	defer func() { c.including = c.including[:len(c.including)-1] }()
+
+	if strings.HasSuffix(filename, ".toml") {
+		return c.readTOML(filename)
+	}
+	return c.readLegacy(filename)
+}
+
+// include reads the config files matching pattern, in lexical order, and returns a ConfigErrors.
+// Relative patterns are relative to the directory of the including file.
+// A pattern with wildcards may match no files at all.  pos is the location
+// of the include directive, used in error messages.
+func (c *Config) include(pattern string, from string, pos string) error {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(from), pattern)
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return ConfigErrors{fmt.Errorf("%s: %w", pos, err)}
+	}
+	if len(files) == 0 && !strings.ContainsAny(pattern, "*?[") {
+		files = []string{pattern}
+	}
+	var errs ConfigErrors
+	for _, f := range files {
+		err := c.readFile(f)
+		if e, ok := err.(ConfigErrors); ok {
+			errs = append(errs, e...)
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", pos, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// readLegacy reads a config file in the line-based format.
+func (c *Config) readLegacy(filename string) error {
 	f, err := os.Open(filename)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer f.Close()
+	var errs ConfigErrors
 	s := bufio.NewScanner(f)
 	lineNum := 0
 	for s.Scan() {
@@ -82,55 +291,453 @@ func ReadConfig(filename string) (*Config, error) {
 			continue
 		}
 		tokens := strings.Fields(line)
-		switch tokens[0] {
-		case "logdir":
-			if len(tokens) != 2 {
-				return nil, fmt.Errorf("syntax error in %s line %d", filename, lineNum)
+		if tokens[0] == "include" {
+			if len(tokens) < 2 {
+				errs = append(errs, fmt.Errorf("syntax error in %s line %d", filename, lineNum))
 			}
-			c.Logdir = tokens[1]
-		case "port":
-			if len(tokens) != 2 {
-				return nil, fmt.Errorf("syntax error in %s line %d", filename, lineNum)
+			for _, pattern := range tokens[1:] {
+				if err := c.include(pattern, filename, fmt.Sprintf("%s:%d", filename, lineNum)); err != nil {
+					errs = append(errs, err.(ConfigErrors)...)
+				}
 			}
-			c.Port, err = strconv.Atoi(tokens[1])
-			if err != nil {
-				return nil, fmt.Errorf("%s line %d: %w", filename, lineNum, err)
+			continue
+		}
+		if err := c.directive(tokens, fmt.Sprintf("%s:%d", filename, lineNum)); err != nil {
+			if err == errSyntax {
+				errs = append(errs, fmt.Errorf("syntax error in %s line %d", filename, lineNum))
+			} else {
+				errs = append(errs, fmt.Errorf("error in %s line %d: %w", filename, lineNum, err))
 			}
-		case "gateway":
-			if len(tokens) < 2 {
-				return nil, fmt.Errorf("syntax error in %s line %d", filename, lineNum)
+		}
+	}
+	if err := s.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// tomlTables lists the tables accepted in TOML config files, and their keys.
+// Required keys are marked with a "!".
+var tomlTables = map[string][]string{
+	"gateway": {"!address", "groups", "lines"},
+	"device":  {"!address", "!name", "room"},
+	"address": {"!address", "!dpt", "!name", "description", "unit", "room", "flags"},
+	"expect":  {"!address", "!interval"},
+	"alert":   {"!type", "args"},
+	"rule":    {"!name", "!address", "!op", "!value", "for", "hysteresis", "renotify"},
+}
+
+// AddressFlags are the valid flags for group addresses.
+var AddressFlags = []string{"readonly", "hidden"}
+
+// readTOML reads a config file in TOML format.  Top-level keys are the same
+// as the directives in the legacy format; gateways, devices, addresses, expectations,
+// alerts and rules are arrays of tables, as in "[[address]]".
+func (c *Config) readTOML(filename string) error {
+	doc, err := parseTOML(filename)
+	if doc == nil {
+		return err
+	}
+	var errs ConfigErrors
+	if e, ok := err.(ConfigErrors); ok {
+		errs = append(errs, e...)
+	}
+
+	for _, key := range doc.Root.Order {
+		tokens := []string{key}
+		if strs, ok := doc.Root.Strings(key); ok {
+			tokens = append(tokens, strs...)
+		} else {
+			errs = append(errs, doc.Root.errorf(key, "invalid value for %s", key))
+			continue
+		}
+		if key == "include" {
+			for _, pattern := range tokens[1:] {
+				if err := c.include(pattern, filename, doc.Root.pos(key)); err != nil {
+					errs = append(errs, err.(ConfigErrors)...)
+				}
 			}
-			gw := Gateway{Address: tokens[1]}
-			for _, g := range tokens[2:] {
-				gw.Groups = append(gw.Groups, g)
+			continue
+		}
+		if err := c.directive(tokens, doc.Root.pos(key)); err != nil {
+			errs = append(errs, doc.Root.errorf(key, "%s: %v", key, err))
+		}
+	}
+
+	for _, t := range doc.Tables {
+		keys, ok := tomlTables[t.Name]
+		if !ok {
+			errs = append(errs, t.errorf("", "unknown table [%s]", t.Name))
+			continue
+		}
+		valid := true
+		allowed := make(map[string]bool)
+		for _, k := range keys {
+			name := strings.TrimPrefix(k, "!")
+			allowed[name] = true
+			if k[0] == '!' {
+				if _, ok := t.Keys[name]; !ok {
+					errs = append(errs, t.errorf("", "[%s]: missing %q", t.Name, name))
+					valid = false
+				}
+			}
+		}
+		for _, k := range t.Order {
+			if !allowed[k] {
+				errs = append(errs, t.errorf(k, "[%s]: unknown key %q", t.Name, k))
+			} else if _, ok := t.Strings(k); !ok {
+				errs = append(errs, t.errorf(k, "[%s]: invalid value for %q", t.Name, k))
+				valid = false
 			}
-			c.Gateways = append(c.Gateways, gw)
-		case "device":
-			if len(tokens) != 3 {
-				return nil, fmt.Errorf("syntax error in %s line %d", filename, lineNum)
+		}
+		if !valid {
+			continue
+		}
+		if err := c.tomlTable(t); err != nil {
+			errs = append(errs, t.errorf("", "[%s]: %v", t.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// tomlTable processes a table of a TOML config file, whose keys have already been checked.
+func (c *Config) tomlTable(t *tomlTable) error {
+	str := func(key string) string {
+		s, _ := t.String(key)
+		return s
+	}
+	switch t.Name {
+	case "gateway":
+		groups, _ := t.Strings("groups")
+		lines, _ := t.Strings("lines")
+		return c.directive(append(append([]string{"gateway", str("address")}, groups...), lines...), t.pos(""))
+	case "device":
+		addr, err := cemi.NewIndividualAddrString(str("address"))
+		if err != nil {
+			return err
+		}
+		if room, ok := t.String("room"); ok {
+			c.DeviceRooms[addr] = room
+		}
+		return c.addDevice(addr, str("name"), t.pos(""))
+	case "address":
+		addr, err := cemi.NewGroupAddrString(str("address"))
+		if err != nil {
+			return err
+		}
+		nt := addrNameType{
+			Name:        str("name"),
+			DPT:         str("dpt"),
+			Description: str("description"),
+			Unit:        str("unit"),
+			Room:        str("room"),
+		}
+		nt.Flags, _ = t.Strings("flags")
+		for _, f := range nt.Flags {
+			if !hasFlag(AddressFlags, f) {
+				return fmt.Errorf("unknown flag %q", f)
 			}
-			// fmt.Printf("line %d: new device: %v\n", lineNum, tokens)
-			addr, err := cemi.NewIndividualAddrString(tokens[1])
-			if err != nil {
-				return nil, fmt.Errorf("error in %s line %d: %w", filename, lineNum, err)
+		}
+		return c.addAddress(addr, nt, t.pos(""))
+	case "expect":
+		return c.directive([]string{"expect", str("address"), str("interval")}, t.pos(""))
+	case "alert":
+		args, _ := t.Strings("args")
+		return c.directive(append([]string{"alert", str("type")}, args...), t.pos(""))
+	case "rule":
+		tokens := []string{"rule", str("name"), str("address"), str("op"), str("value")}
+		for _, k := range []string{"for", "hysteresis", "renotify"} {
+			if v, ok := t.String(k); ok {
+				tokens = append(tokens, k, v)
 			}
-			c.Devices[addr] = tokens[2]
-		case "address":
-			if len(tokens) != 4 {
-				return nil, fmt.Errorf("syntax error in %s line %d", filename, lineNum)
+		}
+		return c.directive(tokens, t.pos(""))
+	}
+	return nil
+}
+
+// hasFlag returns true if flag is in flags.
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// directive processes a line of the config file, split in tokens.
+// pos is the location of the line, used in error messages.
+func (c *Config) directive(tokens []string, pos string) error {
+	var err error
+	switch tokens[0] {
+	case "logdir":
+		if len(tokens) != 2 {
+			return errSyntax
+		}
+		c.Logdir = tokens[1]
+	case "loglevel":
+		if len(tokens) != 2 {
+			return errSyntax
+		}
+		c.LogLevel, err = ParseLogLevel(tokens[1])
+		if err != nil {
+			return err
+		}
+	case "logformat":
+		if len(tokens) != 2 || (tokens[1] != "text" && tokens[1] != "json") {
+			return errSyntax
+		}
+		c.LogFormat = tokens[1]
+	case "logoutput":
+		if len(tokens) != 2 {
+			return errSyntax
+		}
+		c.LogOutput = tokens[1]
+	case "binlog":
+		if len(tokens) != 2 {
+			return errSyntax
+		}
+		c.Binlog, err = parseBool(tokens[1])
+		if err != nil {
+			return err
+		}
+	case "snapshot":
+		if len(tokens) != 2 {
+			return errSyntax
+		}
+		c.Snapshot = tokens[1]
+	case "snapshotinterval":
+		if len(tokens) != 2 {
+			return errSyntax
+		}
+		c.SnapshotInterval, err = time.ParseDuration(tokens[1])
+		if err == nil && c.SnapshotInterval <= 0 {
+			err = fmt.Errorf("invalid snapshot interval %s", tokens[1])
+		}
+		if err != nil {
+			return err
+		}
+	case "snapshotcompress":
+		if len(tokens) != 2 {
+			return errSyntax
+		}
+		c.SnapshotCompress, err = parseBool(tokens[1])
+		if err != nil {
+			return err
+		}
+	case "snapshotkeep":
+		if len(tokens) != 2 {
+			return errSyntax
+		}
+		c.SnapshotKeep, err = strconv.Atoi(tokens[1])
+		if err != nil {
+			return err
+		}
+	case "jsonlog", "logcompress":
+		if len(tokens) != 2 {
+			return errSyntax
+		}
+		b, err := parseBool(tokens[1])
+		if err != nil {
+			return err
+		}
+		if tokens[0] == "jsonlog" {
+			c.JSONLog = b
+		} else {
+			c.LogCompress = b
+		}
+	case "logmaxsize":
+		if len(tokens) != 2 {
+			return errSyntax
+		}
+		c.LogMaxSize, err = parseSize(tokens[1])
+		if err != nil {
+			return err
+		}
+	case "logretention":
+		if len(tokens) != 2 {
+			return errSyntax
+		}
+		c.LogRetention, err = strconv.Atoi(tokens[1])
+		if err != nil {
+			return err
+		}
+	case "history":
+		if len(tokens) != 2 {
+			return errSyntax
+		}
+		c.History, err = strconv.Atoi(tokens[1])
+		if err != nil {
+			return err
+		}
+	case "room":
+		if len(tokens) < 3 {
+			return errSyntax
+		}
+		for _, target := range tokens[2:] {
+			c.roomRefs = append(c.roomRefs, roomRef{Room: tokens[1], Target: target, Pos: pos})
+		}
+	case "etsproject":
+		if len(tokens) != 2 {
+			return errSyntax
+		}
+		c.ETSProject = tokens[1]
+	case "etspasswordfile":
+		if len(tokens) != 2 {
+			return errSyntax
+		}
+		c.ETSPasswordFile = tokens[1]
+	case "port":
+		if len(tokens) != 2 {
+			return errSyntax
+		}
+		c.Port, err = strconv.Atoi(tokens[1])
+		if err != nil {
+			return err
+		}
+	case "gateway":
+		if len(tokens) < 2 {
+			return errSyntax
+		}
+		// the rest are group address prefixes ("1/") and lines ("1.1")
+		gw := Gateway{Address: tokens[1], Pos: pos}
+		for _, g := range tokens[2:] {
+			if !strings.Contains(g, ".") {
+				gw.Groups = append(gw.Groups, g)
+				continue
 			}
-			aAddr := tokens[1]
-			aDPT := tokens[2]
-			aName := tokens[3]
-			// fmt.Printf("line %d: new address: %v\n", lineNum, tokens)
-			addr, err := cemi.NewGroupAddrString(aAddr)
-			if err != nil {
-				return nil, fmt.Errorf("error in %s line %d: %w", filename, lineNum, err)
+			if _, err := cemi.NewIndividualAddrString(g + ".0"); err != nil || strings.Count(g, ".") != 1 {
+				return fmt.Errorf("invalid line %q", g)
 			}
-			c.Addresses[addr] = addrNameType{Name: aName, DPT: aDPT}
-		default:
-			return nil, fmt.Errorf("syntax error in %s line %d: unrecognized token %s", filename, lineNum, tokens[0])
+			gw.Lines = append(gw.Lines, g)
+		}
+		c.Gateways = append(c.Gateways, gw)
+	case "device":
+		if len(tokens) != 3 {
+			return errSyntax
+		}
+		// fmt.Printf("line %d: new device: %v\n", lineNum, tokens)
+		addr, err := cemi.NewIndividualAddrString(tokens[1])
+		if err != nil {
+			return err
+		}
+		return c.addDevice(addr, tokens[2], pos)
+	case "address":
+		if len(tokens) != 4 {
+			return errSyntax
+		}
+		aAddr := tokens[1]
+		aDPT := tokens[2]
+		aName := tokens[3]
+		// fmt.Printf("line %d: new address: %v\n", lineNum, tokens)
+		addr, err := cemi.NewGroupAddrString(aAddr)
+		if err != nil {
+			return err
+		}
+		return c.addAddress(addr, addrNameType{Name: aName, DPT: aDPT}, pos)
+	case "expect":
+		if len(tokens) != 3 {
+			return errSyntax
+		}
+		var e Expectation
+		if strings.Contains(tokens[1], "/") {
+			e.IsGroup = true
+			e.Group, err = cemi.NewGroupAddrString(tokens[1])
+		} else {
+			e.Device, err = cemi.NewIndividualAddrString(tokens[1])
+		}
+		if err != nil {
+			return err
+		}
+		e.Interval, err = time.ParseDuration(tokens[2])
+		if err != nil {
+			return err
+		}
+		c.Expects = append(c.Expects, e)
+	case "alert":
+		if len(tokens) < 2 {
+			return errSyntax
+		}
+		ac := AlertConfig{Type: tokens[1], Args: tokens[2:]}
+		if _, err := NewAlertSink(ac); err != nil {
+			return err
 		}
+		c.Alerts = append(c.Alerts, ac)
+	case "rule":
+		r, err := ParseRule(tokens)
+		if err != nil {
+			return err
+		}
+		r.Pos = pos
+		c.Rules = append(c.Rules, r)
+	default:
+		return fmt.Errorf("unrecognized token %s", tokens[0])
 	}
-	return &c, nil
+	return nil
+}
+
+// addDevice adds a device to the config, checking that it is not defined twice.
+func (c *Config) addDevice(addr cemi.IndividualAddr, name string, pos string) error {
+	if old, ok := c.devicePos[addr]; ok {
+		return fmt.Errorf("device %v already defined in %s", addr, old)
+	}
+	c.Devices[addr] = name
+	c.devicePos[addr] = pos
+	return nil
+}
+
+// addAddress adds a group address to the config, checking that it is not defined twice.
+func (c *Config) addAddress(addr cemi.GroupAddr, nt addrNameType, pos string) error {
+	if old, ok := c.Addresses[addr]; ok {
+		return fmt.Errorf("group address %v already defined in %s", addr, old.Pos)
+	}
+	nt.Pos = pos
+	c.Addresses[addr] = nt
+	return nil
+}
+
+// parseBool accepts "yes", "no", "on", "off" and everything accepted by strconv.ParseBool.
+func parseBool(str string) (bool, error) {
+	switch strings.ToLower(str) {
+	case "yes", "on":
+		return true, nil
+	case "no", "off":
+		return false, nil
+	}
+	return strconv.ParseBool(str)
+}
+
+// parseSize parses a number of bytes, with an optional K, M or G suffix.
+func parseSize(str string) (int64, error) {
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(str, "K"):
+		mult = 1024
+	case strings.HasSuffix(str, "M"):
+		mult = 1024 * 1024
+	case strings.HasSuffix(str, "G"):
+		mult = 1024 * 1024 * 1024
+	}
+	if mult > 1 {
+		str = str[:len(str)-1]
+	}
+	n, err := strconv.ParseInt(str, 10, 64)
+	return n * mult, err
+}
+
+// groupAddr returns the group address given as a string or as its name in the config.
+func (c *Config) groupAddr(str string) (cemi.GroupAddr, error) {
+	for key, val := range c.Addresses {
+		if str == val.Name {
+			return key, nil
+		}
+	}
+	return cemi.NewGroupAddrString(str)
 }
@@ -17,13 +17,13 @@ func GetDPTAsString(v dpt.DatapointValue) string {
 	Val = Val.Elem()
 	switch Val.Kind() {
 	case reflect.Bool:
-		return strconv.FormatBool(Val.Elem().Bool())
+		return strconv.FormatBool(Val.Bool())
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return fmt.Sprint(Val.Elem().Int())
+		return fmt.Sprint(Val.Int())
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return fmt.Sprint(Val.Elem().Uint())
+		return fmt.Sprint(Val.Uint())
 	case reflect.Float32, reflect.Float64:
-		return fmt.Sprintf("%f", Val.Elem().Float())
+		return fmt.Sprintf("%f", Val.Float())
 	default:
 		return fmt.Sprint(v.Pack())
 	}
@@ -39,16 +39,16 @@ func GetDPT(v dpt.DatapointValue) (float64, error) {
 	Val = Val.Elem()
 	switch Val.Kind() {
 	case reflect.Bool:
-		if Val.Elem().Bool() {
+		if Val.Bool() {
 			return 1.0, nil
 		}
 		return 0.0, nil
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return float64(Val.Elem().Int()), nil
+		return float64(Val.Int()), nil
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return float64(Val.Elem().Uint()), nil
+		return float64(Val.Uint()), nil
 	case reflect.Float32, reflect.Float64:
-		return Val.Elem().Float(), nil
+		return Val.Float(), nil
 	default:
 		return 0.0, fmt.Errorf("GetDPT: cannot get element: underlying type is %v", Val.Kind())
 	}
@@ -135,3 +135,19 @@ func NewDPT(dptType string, value float64) (dpt.DatapointValue, error) {
 	err := SetDPT(v, value)
 	return v, err
 }
+
+// decodeMsg returns the value of a telegram, using the DPT of its destination in the config file.
+func decodeMsg(k knxMsg) (dpt.DatapointValue, error) {
+	nt, ok := getConfig().Addresses[k.Event.Destination]
+	if !ok {
+		return nil, fmt.Errorf("address %v not in config file", k.Event.Destination)
+	}
+	dp, ok := dpt.Produce(nt.DPT)
+	if !ok {
+		return nil, fmt.Errorf("unknown type %v in config file", nt.DPT)
+	}
+	if err := dp.Unpack(k.Event.Data); err != nil {
+		return nil, err
+	}
+	return dp, nil
+}
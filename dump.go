@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/cespedes/knxweb/binlog"
+	"github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// telegramFilter selects telegrams by time, group address, source and command.
+type telegramFilter struct {
+	From, To time.Time
+	Addrs    []cemi.GroupAddr
+	Sources  []cemi.IndividualAddr
+	Command  string
+}
+
+func (f telegramFilter) Match(k knxMsg) bool {
+	if !f.From.IsZero() && k.When.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !k.When.Before(f.To) {
+		return false
+	}
+	if f.Command != "" && f.Command != commandName(k.Event.Command) {
+		return false
+	}
+	if len(f.Addrs) > 0 {
+		found := false
+		for _, a := range f.Addrs {
+			if a == k.Event.Destination {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Sources) > 0 {
+		found := false
+		for _, a := range f.Sources {
+			if a == k.Event.Source {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func commandName(c knx.GroupCommand) string {
+	switch c {
+	case knx.GroupRead:
+		return "read"
+	case knx.GroupResponse:
+		return "response"
+	case knx.GroupWrite:
+		return "write"
+	default:
+		return "???"
+	}
+}
+
+// dumpCommand implements "knxweb dump": it prints the contents of binary logs.
+func dumpCommand(args []string) int {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	configFile := fs.String("config", "knx.cfg", "config file")
+	from := fs.String("from", "", "show telegrams since this time (YYYY-MM-DD HH:MM:SS)")
+	to := fs.String("to", "", "show telegrams before this time (YYYY-MM-DD HH:MM:SS)")
+	addr := fs.String("addr", "", "show only telegrams sent to this group address or name")
+	source := fs.String("source", "", "show only telegrams sent by this device")
+	command := fs.String("command", "", "show only this command (read, response or write)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s dump [options] <file.knxlog>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := ReadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	config.Store(cfg)
+
+	var f telegramFilter
+	f.Command = *command
+	if *from != "" {
+		if f.From, err = time.ParseInLocation("2006-01-02 15:04:05", *from, time.Local); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	if *to != "" {
+		if f.To, err = time.ParseInLocation("2006-01-02 15:04:05", *to, time.Local); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	if *addr != "" {
+		var s Server
+		if f.Addrs = s.getAddrs(*addr); len(f.Addrs) == 0 {
+			fmt.Fprintf(os.Stderr, "unknown group address %q\n", *addr)
+			return 2
+		}
+	}
+	if *source != "" {
+		a, err := cemi.NewIndividualAddrString(*source)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		f.Sources = []cemi.IndividualAddr{a}
+	}
+
+	status := 0
+	for _, filename := range fs.Args() {
+		if err := dumpBinaryLog(filename, f); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func dumpBinaryLog(filename string, f telegramFilter) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	r, err := binlog.NewReader(file)
+	if err != nil {
+		return err
+	}
+	for {
+		t, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		k := knxMsg{When: t.Time, Where: t.Gateway, Event: t.Event}
+		if !f.Match(k) {
+			continue
+		}
+		// knxMsg.String() starts with the time, with a resolution of seconds:
+		str := k.String()
+		fmt.Printf("%s%s (%s)\n", k.When.Format("2006-01-02 15:04:05.000"), str[19:], t.Gateway)
+	}
+}
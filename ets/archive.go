@@ -0,0 +1,105 @@
+// Package ets reads the files of an ETS export archive (.knxproj or .knxprod):
+// the application programs of the manufacturers, and the projects with their
+// installations (topology and group addresses).
+//
+// It is based on package ets of knx-go, and decodes only what is needed by knxweb.
+package ets
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var (
+	manufacturerFile = regexp.MustCompile(`^(M-[0-9A-Za-z]+)/(M-[0-9A-Za-z]+)_(A-[^/]+)\.xml$`)
+	projectFile      = regexp.MustCompile(`^(P-[0-9A-Za-z]+)/project\.xml$`)
+	installationFile = regexp.MustCompile(`^(P-[0-9A-Za-z]+)/([0-9]+)\.xml$`)
+)
+
+// ExportArchive is an ETS export archive.
+type ExportArchive struct {
+	closer            io.Closer
+	ProjectFiles      []ProjectFile
+	ManufacturerFiles []ManufacturerFile
+}
+
+// OpenExportArchive opens the export archive in path.
+func OpenExportArchive(path string) (*ExportArchive, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	a := NewExportArchive(&r.Reader)
+	a.closer = r
+	return a, nil
+}
+
+// NewExportArchive returns the export archive in r, which may be read from memory.
+func NewExportArchive(r *zip.Reader) *ExportArchive {
+	a := &ExportArchive{}
+	projects := make(map[ProjectID]*ProjectFile)
+	project := func(id ProjectID) *ProjectFile {
+		if p, ok := projects[id]; ok {
+			return p
+		}
+		p := &ProjectFile{ProjectID: id}
+		projects[id] = p
+		return p
+	}
+	for _, f := range r.File {
+		if m := manufacturerFile.FindStringSubmatch(f.Name); m != nil && m[1] == m[2] {
+			a.ManufacturerFiles = append(a.ManufacturerFiles, ManufacturerFile{
+				File:           f,
+				ManufacturerID: ManufacturerID(m[1]),
+				ContentID:      m[3],
+			})
+		} else if m := projectFile.FindStringSubmatch(f.Name); m != nil {
+			project(ProjectID(m[1])).File = f
+		} else if m := installationFile.FindStringSubmatch(f.Name); m != nil {
+			id, err := strconv.ParseUint(m[2], 10, 0)
+			if err != nil {
+				continue
+			}
+			p := project(ProjectID(m[1]))
+			p.InstallationFiles = append(p.InstallationFiles, InstallationFile{
+				File:           f,
+				ProjectID:      p.ProjectID,
+				InstallationID: uint(id),
+			})
+		}
+	}
+	for _, p := range projects {
+		if p.File == nil {
+			// installation files without a project file are ignored
+			continue
+		}
+		sort.Slice(p.InstallationFiles, func(i, j int) bool {
+			return p.InstallationFiles[i].InstallationID < p.InstallationFiles[j].InstallationID
+		})
+		a.ProjectFiles = append(a.ProjectFiles, *p)
+	}
+	sort.Slice(a.ProjectFiles, func(i, j int) bool { return a.ProjectFiles[i].ProjectID < a.ProjectFiles[j].ProjectID })
+	return a
+}
+
+// Close closes the archive, if it was opened by OpenExportArchive.
+func (a *ExportArchive) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+// decode decodes the XML file f into v.
+func decode(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
@@ -0,0 +1,173 @@
+package ets
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+var testFiles = map[string]string{
+	"knx_master.xml":      `<KNX/>`,
+	"M-0083/Hardware.xml": `<KNX/>`,
+	"M-0083/M-0083_A-0001-10-ABCD.xml": `<KNX xmlns="http://knx.org/xml/project/20">
+ <ManufacturerData>
+  <Manufacturer RefId="M-0083">
+   <ApplicationPrograms>
+    <ApplicationProgram Id="M-0083_A-0001-10-ABCD" Name="Switch" ApplicationVersion="16">
+     <Static>
+      <ComObjectTable>
+       <ComObject Id="M-0083_A-0001-10-ABCD_O-1" Name="Switch" Text="Channel A" FunctionText="On/Off" ObjectSize="1 Bit" DatapointType="DPST-1-1" CommunicationFlag="Enabled" ReadFlag="Disabled" WriteFlag="Enabled" TransmitFlag="Enabled" UpdateFlag="Disabled" ReadOnInitFlag="Disabled"/>
+      </ComObjectTable>
+      <ComObjectRefs>
+       <ComObjectRef Id="M-0083_A-0001-10-ABCD_O-1_R-1" RefId="M-0083_A-0001-10-ABCD_O-1" Text="Light" ReadFlag="Enabled"/>
+      </ComObjectRefs>
+     </Static>
+    </ApplicationProgram>
+   </ApplicationPrograms>
+  </Manufacturer>
+ </ManufacturerData>
+</KNX>`,
+	"P-0123/project.xml": `<KNX xmlns="http://knx.org/xml/project/20">
+ <Project Id="P-0123"><ProjectInformation Name="Home"/></Project>
+</KNX>`,
+	"P-0123/0.xml": `<KNX xmlns="http://knx.org/xml/project/20">
+ <Project Id="P-0123">
+  <Installations>
+   <Installation Name="">
+    <Topology>
+     <Area Id="P-0123-0_A-1" Name="House" Address="1">
+      <Line Id="P-0123-0_L-1" Name="Ground floor" Address="1">
+       <DeviceInstance Id="P-0123-0_DI-1" Name="Actuator" Address="5">
+        <ComObjectInstanceRefs>
+         <ComObjectInstanceRef RefId="O-1_R-1" Links="GA-1 GA-2"/>
+        </ComObjectInstanceRefs>
+       </DeviceInstance>
+      </Line>
+      <Line Id="P-0123-0_L-2" Name="First floor" Address="2">
+       <Segment Id="P-0123-0_S-1">
+        <DeviceInstance Id="P-0123-0_DI-2" Name="Push button" Address="7"/>
+       </Segment>
+      </Line>
+     </Area>
+    </Topology>
+    <GroupAddresses>
+     <GroupRanges>
+      <GroupRange Name="Lights">
+       <GroupRange Name="Kitchen">
+        <GroupAddress Id="P-0123-0_GA-1" Name="Kitchen light" Address="257"/>
+       </GroupRange>
+       <GroupAddress Id="P-0123-0_GA-2" Name="All lights" Address="256"/>
+      </GroupRange>
+     </GroupRanges>
+    </GroupAddresses>
+   </Installation>
+  </Installations>
+ </Project>
+</KNX>`,
+}
+
+func testArchive(t *testing.T) *ExportArchive {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range testFiles {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewExportArchive(r)
+}
+
+func TestArchive(t *testing.T) {
+	a := testArchive(t)
+	if len(a.ManufacturerFiles) != 1 {
+		t.Fatalf("got %d manufacturer files, want 1", len(a.ManufacturerFiles))
+	}
+	if mf := a.ManufacturerFiles[0]; mf.ManufacturerID != "M-0083" || mf.ContentID != "A-0001-10-ABCD" {
+		t.Errorf("manufacturer file: got %q %q", mf.ManufacturerID, mf.ContentID)
+	}
+	if len(a.ProjectFiles) != 1 || len(a.ProjectFiles[0].InstallationFiles) != 1 {
+		t.Fatalf("got %d project files, want 1 with 1 installation", len(a.ProjectFiles))
+	}
+	info, err := a.ProjectFiles[0].Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ID != "P-0123" || info.Name != "Home" {
+		t.Errorf("project info: got %+v", info)
+	}
+}
+
+func TestManufacturer(t *testing.T) {
+	mf := testArchive(t).ManufacturerFiles[0]
+	md, err := mf.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Manufacturer != "M-0083" || len(md.Programs) != 1 {
+		t.Fatalf("got %+v", md)
+	}
+	prog := md.Programs[0]
+	if prog.Version != 16 || len(prog.Objects) != 1 || len(prog.ObjectRefs) != 1 {
+		t.Fatalf("got %+v", prog)
+	}
+	obj := prog.Objects[0]
+	if obj.Text != "Channel A" || obj.DatapointType != "DPST-1-1" || !obj.CommunicationFlag || obj.ReadFlag || !obj.WriteFlag {
+		t.Errorf("object: got %+v", obj)
+	}
+	ref := prog.ObjectRefs[0]
+	if ref.Text == nil || *ref.Text != "Light" || ref.ReadFlag == nil || !*ref.ReadFlag {
+		t.Errorf("object ref: got %+v", ref)
+	}
+	if ref.FunctionText != nil || ref.WriteFlag != nil {
+		t.Errorf("object ref: attributes which are not overridden should be nil")
+	}
+}
+
+func TestInstallation(t *testing.T) {
+	inf := testArchive(t).ProjectFiles[0].InstallationFiles[0]
+	p, err := inf.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != "P-0123" || len(p.Installations) != 1 {
+		t.Fatalf("got %+v", p)
+	}
+	inst := p.Installations[0]
+	if len(inst.Topology) != 1 || len(inst.Topology[0].Lines) != 2 {
+		t.Fatalf("topology: got %+v", inst.Topology)
+	}
+	lines := inst.Topology[0].Lines
+	dev := lines[0].Devices[0]
+	if dev.Name != "Actuator" || dev.Address != 5 || len(dev.ComObjects) != 1 {
+		t.Fatalf("device: got %+v", dev)
+	}
+	if co := dev.ComObjects[0]; co.RefID != "O-1_R-1" || len(co.Links) != 2 || co.Links[1] != "GA-2" {
+		t.Errorf("group object: got %+v", co)
+	}
+	if len(lines[1].Devices) != 1 || lines[1].Devices[0].Address != 7 {
+		t.Errorf("devices in segments: got %+v", lines[1].Devices)
+	}
+	want := []GroupAddress{
+		{ID: "P-0123-0_GA-2", Name: "All lights", Address: cemi.GroupAddr(256)},
+		{ID: "P-0123-0_GA-1", Name: "Kitchen light", Address: cemi.GroupAddr(257)},
+	}
+	if len(inst.GroupAddresses) != len(want) {
+		t.Fatalf("group addresses: got %+v", inst.GroupAddresses)
+	}
+	for i := range want {
+		if inst.GroupAddresses[i] != want[i] {
+			t.Errorf("group address %d: got %+v, want %+v", i, inst.GroupAddresses[i], want[i])
+		}
+	}
+}
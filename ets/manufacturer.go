@@ -0,0 +1,194 @@
+package ets
+
+import (
+	"archive/zip"
+	"encoding/xml"
+)
+
+// ManufacturerID identifies a manufacturer, as in "M-0083".
+type ManufacturerID string
+
+// ComObjectID identifies a group object of an application program.
+type ComObjectID string
+
+// ComObjectRefID identifies a reference to a group object of an application program.
+type ComObjectRefID string
+
+// ComObject is a group object defined by an application program.
+type ComObject struct {
+	ID                ComObjectID
+	Name              string
+	Text              string
+	Description       string
+	FunctionText      string
+	ObjectSize        string
+	DatapointType     string
+	Priority          string
+	ReadFlag          bool
+	WriteFlag         bool
+	CommunicationFlag bool
+	TransmitFlag      bool
+	UpdateFlag        bool
+	ReadOnInitFlag    bool
+}
+
+// ComObjectRef is a reference to a group object, which may override some of its attributes.
+// Attributes which are not overridden are nil.
+type ComObjectRef struct {
+	ID                ComObjectRefID
+	RefID             ComObjectID
+	Name              *string
+	Text              *string
+	Description       *string
+	FunctionText      *string
+	ObjectSize        *string
+	DatapointType     *string
+	Priority          *string
+	ReadFlag          *bool
+	WriteFlag         *bool
+	CommunicationFlag *bool
+	TransmitFlag      *bool
+	UpdateFlag        *bool
+	ReadOnInitFlag    *bool
+}
+
+// ApplicationProgram is an application program of a manufacturer.
+type ApplicationProgram struct {
+	ID         string
+	Name       string
+	Version    uint
+	Objects    []ComObject
+	ObjectRefs []ComObjectRef
+}
+
+// ManufacturerData is the contents of a manufacturer file.
+type ManufacturerData struct {
+	Manufacturer ManufacturerID
+	Programs     []ApplicationProgram
+}
+
+// ManufacturerFile is a file with an application program, as in "M-0083/M-0083_A-00B0-32-0DFC.xml".
+type ManufacturerFile struct {
+	*zip.File
+	ManufacturerID ManufacturerID
+	ContentID      string
+}
+
+// Decode reads the application programs in the file.
+func (m *ManufacturerFile) Decode() (*ManufacturerData, error) {
+	var doc struct {
+		Manufacturers []struct {
+			RefID    ManufacturerID `xml:"RefId,attr"`
+			Programs []struct {
+				ID         string            `xml:"Id,attr"`
+				Name       string            `xml:"Name,attr"`
+				Version    uint              `xml:"ApplicationVersion,attr"`
+				Objects    []xmlComObject    `xml:"Static>ComObjectTable>ComObject"`
+				ObjectRefs []xmlComObjectRef `xml:"Static>ComObjectRefs>ComObjectRef"`
+			} `xml:"ApplicationPrograms>ApplicationProgram"`
+		} `xml:"ManufacturerData>Manufacturer"`
+	}
+	if err := decode(m.File, &doc); err != nil {
+		return nil, err
+	}
+
+	data := &ManufacturerData{Manufacturer: m.ManufacturerID}
+	for _, manu := range doc.Manufacturers {
+		if manu.RefID != "" {
+			data.Manufacturer = manu.RefID
+		}
+		for _, p := range manu.Programs {
+			prog := ApplicationProgram{ID: p.ID, Name: p.Name, Version: p.Version}
+			for _, o := range p.Objects {
+				prog.Objects = append(prog.Objects, ComObject{
+					ID:                o.ID,
+					Name:              o.Name,
+					Text:              o.Text,
+					Description:       o.Description,
+					FunctionText:      o.FunctionText,
+					ObjectSize:        o.ObjectSize,
+					DatapointType:     o.DatapointType,
+					Priority:          o.Priority,
+					ReadFlag:          bool(o.ReadFlag),
+					WriteFlag:         bool(o.WriteFlag),
+					CommunicationFlag: bool(o.CommunicationFlag),
+					TransmitFlag:      bool(o.TransmitFlag),
+					UpdateFlag:        bool(o.UpdateFlag),
+					ReadOnInitFlag:    bool(o.ReadOnInitFlag),
+				})
+			}
+			for _, r := range p.ObjectRefs {
+				prog.ObjectRefs = append(prog.ObjectRefs, ComObjectRef{
+					ID:                r.ID,
+					RefID:             r.RefID,
+					Name:              r.Name,
+					Text:              r.Text,
+					Description:       r.Description,
+					FunctionText:      r.FunctionText,
+					ObjectSize:        r.ObjectSize,
+					DatapointType:     r.DatapointType,
+					Priority:          r.Priority,
+					ReadFlag:          r.ReadFlag.ptr(),
+					WriteFlag:         r.WriteFlag.ptr(),
+					CommunicationFlag: r.CommunicationFlag.ptr(),
+					TransmitFlag:      r.TransmitFlag.ptr(),
+					UpdateFlag:        r.UpdateFlag.ptr(),
+					ReadOnInitFlag:    r.ReadOnInitFlag.ptr(),
+				})
+			}
+			data.Programs = append(data.Programs, prog)
+		}
+	}
+	return data, nil
+}
+
+type xmlComObject struct {
+	ID                ComObjectID `xml:"Id,attr"`
+	Name              string      `xml:"Name,attr"`
+	Text              string      `xml:"Text,attr"`
+	Description       string      `xml:"Description,attr"`
+	FunctionText      string      `xml:"FunctionText,attr"`
+	ObjectSize        string      `xml:"ObjectSize,attr"`
+	DatapointType     string      `xml:"DatapointType,attr"`
+	Priority          string      `xml:"Priority,attr"`
+	ReadFlag          flag        `xml:"ReadFlag,attr"`
+	WriteFlag         flag        `xml:"WriteFlag,attr"`
+	CommunicationFlag flag        `xml:"CommunicationFlag,attr"`
+	TransmitFlag      flag        `xml:"TransmitFlag,attr"`
+	UpdateFlag        flag        `xml:"UpdateFlag,attr"`
+	ReadOnInitFlag    flag        `xml:"ReadOnInitFlag,attr"`
+}
+
+type xmlComObjectRef struct {
+	ID                ComObjectRefID `xml:"Id,attr"`
+	RefID             ComObjectID    `xml:"RefId,attr"`
+	Name              *string        `xml:"Name,attr"`
+	Text              *string        `xml:"Text,attr"`
+	Description       *string        `xml:"Description,attr"`
+	FunctionText      *string        `xml:"FunctionText,attr"`
+	ObjectSize        *string        `xml:"ObjectSize,attr"`
+	DatapointType     *string        `xml:"DatapointType,attr"`
+	Priority          *string        `xml:"Priority,attr"`
+	ReadFlag          *flag          `xml:"ReadFlag,attr"`
+	WriteFlag         *flag          `xml:"WriteFlag,attr"`
+	CommunicationFlag *flag          `xml:"CommunicationFlag,attr"`
+	TransmitFlag      *flag          `xml:"TransmitFlag,attr"`
+	UpdateFlag        *flag          `xml:"UpdateFlag,attr"`
+	ReadOnInitFlag    *flag          `xml:"ReadOnInitFlag,attr"`
+}
+
+// flag is a communication flag, which is "Enabled" or "Disabled" in the XML files.
+type flag bool
+
+func (f *flag) UnmarshalXMLAttr(attr xml.Attr) error {
+	*f = attr.Value == "Enabled"
+	return nil
+}
+
+func (f *flag) ptr() *bool {
+	if f == nil {
+		return nil
+	}
+	b := bool(*f)
+	return &b
+}
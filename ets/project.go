@@ -0,0 +1,208 @@
+package ets
+
+import (
+	"archive/zip"
+	"strings"
+
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// ProjectID identifies a project, as in "P-0123".
+type ProjectID string
+
+// ComObjectInstanceRef is a group object of a device, with the group addresses it is linked to.
+type ComObjectInstanceRef struct {
+	RefID         ComObjectRefID // relative to the application program, as in "O-1_R-1"
+	DatapointType string
+	Links         []string // IDs of group addresses, which may be relative to the project
+}
+
+// DeviceInstance is a device of the topology.
+type DeviceInstance struct {
+	ID         string
+	Name       string
+	Address    uint // device number in its line
+	ComObjects []ComObjectInstanceRef
+}
+
+// Line is a line of an area.
+type Line struct {
+	ID      string
+	Name    string
+	Address uint
+	Devices []DeviceInstance
+}
+
+// Area is an area of the topology.
+type Area struct {
+	ID      string
+	Name    string
+	Address uint
+	Lines   []Line
+}
+
+// GroupAddress is a group address.
+type GroupAddress struct {
+	ID      string
+	Name    string
+	Address cemi.GroupAddr
+}
+
+// Installation is an installation of a project.
+type Installation struct {
+	Name           string
+	Topology       []Area
+	GroupAddresses []GroupAddress
+}
+
+// Project is the contents of an installation file.
+type Project struct {
+	ID            ProjectID
+	Installations []Installation
+}
+
+// ProjectInfo is the contents of a project file.
+type ProjectInfo struct {
+	ID   ProjectID
+	Name string
+}
+
+// InstallationFile is a file with an installation of a project, as in "P-0123/0.xml".
+type InstallationFile struct {
+	*zip.File
+	ProjectID      ProjectID
+	InstallationID uint
+}
+
+// Decode reads the installations in the file.
+func (i *InstallationFile) Decode() (*Project, error) {
+	var doc struct {
+		Project struct {
+			ID            ProjectID `xml:"Id,attr"`
+			Installations []struct {
+				Name  string `xml:"Name,attr"`
+				Areas []struct {
+					ID      string `xml:"Id,attr"`
+					Name    string `xml:"Name,attr"`
+					Address uint   `xml:"Address,attr"`
+					Lines   []struct {
+						ID       string      `xml:"Id,attr"`
+						Name     string      `xml:"Name,attr"`
+						Address  uint        `xml:"Address,attr"`
+						Devices  []xmlDevice `xml:"DeviceInstance"`
+						Segments []struct {
+							Devices []xmlDevice `xml:"DeviceInstance"`
+						} `xml:"Segment"`
+					} `xml:"Line"`
+				} `xml:"Topology>Area"`
+				Ranges []xmlGroupRange `xml:"GroupAddresses>GroupRanges>GroupRange"`
+			} `xml:"Installations>Installation"`
+		} `xml:"Project"`
+	}
+	if err := decode(i.File, &doc); err != nil {
+		return nil, err
+	}
+
+	p := &Project{ID: doc.Project.ID}
+	for _, inst := range doc.Project.Installations {
+		in := Installation{Name: inst.Name}
+		for _, a := range inst.Areas {
+			area := Area{ID: a.ID, Name: a.Name, Address: a.Address}
+			for _, l := range a.Lines {
+				line := Line{ID: l.ID, Name: l.Name, Address: l.Address}
+				// ETS6 has the devices of a line in its segments
+				devices := l.Devices
+				for _, s := range l.Segments {
+					devices = append(devices, s.Devices...)
+				}
+				for _, d := range devices {
+					line.Devices = append(line.Devices, d.device())
+				}
+				area.Lines = append(area.Lines, line)
+			}
+			in.Topology = append(in.Topology, area)
+		}
+		for _, r := range inst.Ranges {
+			in.GroupAddresses = r.addresses(in.GroupAddresses)
+		}
+		p.Installations = append(p.Installations, in)
+	}
+	return p, nil
+}
+
+type xmlDevice struct {
+	ID         string `xml:"Id,attr"`
+	Name       string `xml:"Name,attr"`
+	Address    uint   `xml:"Address,attr"`
+	ComObjects []struct {
+		RefID         ComObjectRefID `xml:"RefId,attr"`
+		DatapointType string         `xml:"DatapointType,attr"`
+		Links         string         `xml:"Links,attr"`
+		// ETS4 has the links as connectors instead
+		Send    []xmlConnector `xml:"Connectors>Send"`
+		Receive []xmlConnector `xml:"Connectors>Receive"`
+	} `xml:"ComObjectInstanceRefs>ComObjectInstanceRef"`
+}
+
+type xmlConnector struct {
+	GroupAddressRefID string `xml:"GroupAddressRefId,attr"`
+}
+
+func (d xmlDevice) device() DeviceInstance {
+	dev := DeviceInstance{ID: d.ID, Name: d.Name, Address: d.Address}
+	for _, co := range d.ComObjects {
+		ref := ComObjectInstanceRef{
+			RefID:         co.RefID,
+			DatapointType: co.DatapointType,
+			Links:         strings.Fields(co.Links),
+		}
+		for _, c := range append(co.Send, co.Receive...) {
+			ref.Links = append(ref.Links, c.GroupAddressRefID)
+		}
+		dev.ComObjects = append(dev.ComObjects, ref)
+	}
+	return dev
+}
+
+type xmlGroupRange struct {
+	Ranges    []xmlGroupRange `xml:"GroupRange"`
+	Addresses []struct {
+		ID      string `xml:"Id,attr"`
+		Name    string `xml:"Name,attr"`
+		Address uint16 `xml:"Address,attr"`
+	} `xml:"GroupAddress"`
+}
+
+// addresses appends the group addresses in a group range, and in the ones inside it, to list.
+func (r xmlGroupRange) addresses(list []GroupAddress) []GroupAddress {
+	for _, a := range r.Addresses {
+		list = append(list, GroupAddress{ID: a.ID, Name: a.Name, Address: cemi.GroupAddr(a.Address)})
+	}
+	for _, sub := range r.Ranges {
+		list = sub.addresses(list)
+	}
+	return list
+}
+
+// ProjectFile is the file with the information of a project, as in "P-0123/project.xml".
+type ProjectFile struct {
+	*zip.File
+	ProjectID         ProjectID
+	InstallationFiles []InstallationFile
+}
+
+// Decode reads the information of the project.
+func (p *ProjectFile) Decode() (*ProjectInfo, error) {
+	var doc struct {
+		Project struct {
+			ID   ProjectID `xml:"Id,attr"`
+			Info struct {
+				Name string `xml:"Name,attr"`
+			} `xml:"ProjectInformation"`
+		} `xml:"Project"`
+	}
+	if err := decode(p.File, &doc); err != nil {
+		return nil, err
+	}
+	return &ProjectInfo{ID: doc.Project.ID, Name: doc.Project.Info.Name}, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cespedes/knxweb/knxproj"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// ETSChange is a change between two versions of an ETS project, and what it means
+// for the current config.
+type ETSChange struct {
+	knxproj.Change
+	Impact []string `json:",omitempty"`
+}
+
+// etsImpact returns the consequences of a change in the ETS project for config c.
+func etsImpact(c *Config, ch knxproj.Change, old, new *knxproj.Project) []string {
+	var impact []string
+	add := func(format string, args ...interface{}) {
+		impact = append(impact, fmt.Sprintf(format, args...))
+	}
+
+	switch ch.What {
+	case "group":
+		addr, err := cemi.NewGroupAddrString(ch.Address)
+		if err != nil {
+			return nil
+		}
+		nt, inConfig := c.Addresses[addr]
+		fromETS := inConfig && nt.Pos == c.ETSProject
+		uses := func() {
+			for _, r := range c.Rules {
+				if r.Addr == addr {
+					add("used by rule %s (%s)", r.Name, r.Pos)
+				}
+			}
+			for _, e := range c.Expects {
+				if e.IsGroup && e.Group == addr {
+					add("used by expect %s", e)
+				}
+			}
+		}
+		switch ch.Kind {
+		case "added":
+			if inConfig && !fromETS {
+				add("already defined in %s as %s (%s); the config entry is kept", nt.Pos, nt.Name, nt.DPT)
+			} else if g, ok := findGroup(new, addr); ok && c.ETSProject != "" {
+				add("will be added as %s (%s)", defaultETSNames.group(g), g.DPT)
+			}
+		case "removed":
+			if fromETS {
+				add("%s will be removed", nt.Name)
+			} else if inConfig {
+				add("still defined in %s as %s", nt.Pos, nt.Name)
+			}
+			uses()
+		case "renamed":
+			if inConfig && !fromETS {
+				add("the name in %s, %s, is kept", nt.Pos, nt.Name)
+				break
+			}
+			og, _ := findGroup(old, addr)
+			ng, _ := findGroup(new, addr)
+			oldName, newName := defaultETSNames.group(og), defaultETSNames.group(ng)
+			if oldName != newName {
+				add("name changes from %s to %s", oldName, newName)
+				for _, r := range c.Rules {
+					if r.Target == oldName {
+						add("rule %s (%s) refers to it as %s", r.Name, r.Pos, oldName)
+					}
+				}
+			}
+		case "dpt":
+			if inConfig && !fromETS {
+				if nt.DPT != ch.New {
+					add("defined in %s with DPT %s", nt.Pos, nt.DPT)
+				}
+				break
+			}
+			add("values will be decoded as %s instead of %s", ch.New, ch.Old)
+			uses()
+		}
+	case "device":
+		addr, err := cemi.NewIndividualAddrString(ch.Address)
+		if err != nil {
+			return nil
+		}
+		pos, inConfig := c.devicePos[addr]
+		fromETS := inConfig && pos == c.ETSProject
+		switch ch.Kind {
+		case "added":
+			if inConfig && !fromETS {
+				add("already defined in %s as %s; the config entry is kept", pos, c.Devices[addr])
+			}
+		case "removed":
+			if inConfig && !fromETS {
+				add("still defined in %s as %s", pos, c.Devices[addr])
+			}
+			for _, e := range c.Expects {
+				if !e.IsGroup && e.Device == addr {
+					add("expect %s will send alerts if it is gone", e)
+				}
+			}
+		case "renamed":
+			if inConfig && !fromETS {
+				add("the name in %s, %s, is kept", pos, c.Devices[addr])
+			}
+		}
+	}
+	return impact
+}
+
+func findGroup(p *knxproj.Project, addr cemi.GroupAddr) (knxproj.Group, bool) {
+	for _, g := range p.Groups {
+		if g.Address == addr {
+			return g, true
+		}
+	}
+	return knxproj.Group{}, false
+}
+
+// etsDiffCommand implements "knxweb ets-diff": it prints the changes between two versions
+// of an ETS project and their impact on the config.
+func etsDiffCommand(args []string) int {
+	fs := flag.NewFlagSet("ets-diff", flag.ExitOnError)
+	configFile := fs.String("config", "knx.cfg", "config file")
+	format := fs.String("format", "text", "output format: \"text\" or \"json\"")
+	password := fs.String("password", "", "password of the projects, if protected (default: $"+knxproj.PasswordEnv+")")
+	passwordFile := fs.String("password-file", "", "file with the password of the projects")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s ets-diff [options] <old.knxproj> <new.knxproj>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 || (*format != "text" && *format != "json") {
+		fs.Usage()
+		return 2
+	}
+
+	c, err := ReadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	pw, err := knxproj.Password(*password, *passwordFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var projects [2]*knxproj.Project
+	for i, filename := range fs.Args() {
+		if projects[i], err = knxproj.Open(filename, pw); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+			return 1
+		}
+	}
+
+	var changes []ETSChange
+	for _, ch := range knxproj.Diff(projects[0], projects[1]) {
+		changes = append(changes, ETSChange{Change: ch, Impact: etsImpact(c, ch, projects[0], projects[1])})
+	}
+	if *format == "json" {
+		if changes == nil {
+			changes = []ETSChange{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(changes)
+		return 0
+	}
+	for _, ch := range changes {
+		fmt.Println(ch.Change)
+		for _, i := range ch.Impact {
+			fmt.Printf("\t-> %s\n", i)
+		}
+	}
+	return 0
+}
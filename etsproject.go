@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/cespedes/knxweb/knxproj"
+)
+
+// loadETS reads c.ETSProject, and adds its devices and group addresses to the config.
+// Entries in the config file take precedence; only their missing descriptions and rooms
+// are taken from the project.  The password of protected projects is read from
+// c.ETSPasswordFile or $KNXPROJ_PASSWORD.
+func (c *Config) loadETS() error {
+	password, err := knxproj.Password("", c.ETSPasswordFile)
+	if err != nil {
+		return err
+	}
+	proj, err := knxproj.Open(c.ETSProject, password)
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.ETSProject, err)
+	}
+	c.ETS = proj
+
+	for _, d := range proj.Devices {
+		if _, ok := c.Devices[d.Address]; !ok {
+			c.Devices[d.Address] = defaultETSNames.device(d)
+			c.devicePos[d.Address] = c.ETSProject
+		}
+		if _, ok := c.DeviceRooms[d.Address]; !ok && len(d.Location) > 0 {
+			c.DeviceRooms[d.Address] = roomPath(d.Location)
+		}
+	}
+	used := make(map[string]bool)
+	for _, nt := range c.Addresses {
+		used[nt.Name] = true
+	}
+	for _, g := range proj.Groups {
+		room := roomPath(g.Location)
+		if nt, ok := c.Addresses[g.Address]; ok {
+			if nt.Description == "" {
+				nt.Description = g.Description
+			}
+			if nt.Room == "" {
+				nt.Room = room
+			}
+			c.Addresses[g.Address] = nt
+			continue
+		}
+		if g.DPT == "" {
+			// values could not be decoded
+			continue
+		}
+		c.Addresses[g.Address] = addrNameType{
+			Name:        defaultETSNames.unique(defaultETSNames.group(g), used),
+			DPT:         g.DPT,
+			Description: g.Description,
+			Room:        room,
+			Pos:         c.ETSProject,
+		}
+	}
+	return nil
+}
+
+// roomPath returns the name of a room from its location in the ETS project,
+// as in "house/first floor/kitchen".
+func roomPath(location []string) string {
+	var parts []string
+	for _, l := range location {
+		parts = append(parts, strings.ReplaceAll(strings.TrimSpace(l), "/", "-"))
+	}
+	return strings.Join(parts, "/")
+}
+
+// reloadOnHangup reads the config file again when the program receives a SIGHUP,
+// and logs the changes in the ETS project.  Devices, group addresses, expectations
+// and rules are updated, resolving the names of the addresses again; other changes
+// need a restart.
+func (s *Server) reloadOnHangup(filename string) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	for range sig {
+		appLog.Infof("Received SIGHUP; reading %s", filename)
+		newConfig, err := ReadConfig(filename)
+		if err != nil {
+			appLog.Errorf("%v", err)
+			continue
+		}
+		old := getConfig()
+		if old.ETS != nil && newConfig.ETS != nil {
+			for _, change := range knxproj.Diff(old.ETS, newConfig.ETS) {
+				appLog.Infof("%s: %s", newConfig.ETSProject, change)
+			}
+		}
+		c := *old
+		c.Devices = newConfig.Devices
+		c.Addresses = newConfig.Addresses
+		c.devicePos = newConfig.devicePos
+		c.DeviceRooms = newConfig.DeviceRooms
+		c.ETSProject = newConfig.ETSProject
+		c.ETSPasswordFile = newConfig.ETSPasswordFile
+		c.ETS = newConfig.ETS
+		c.Expects = newConfig.Expects
+		c.Rules = newConfig.Rules
+		config.Store(&c)
+		s.Rules.Init(c.Rules)
+		appLog.Infof("%d devices, %d group addresses, %d expectations and %d rules loaded",
+			len(c.Devices), len(c.Addresses), len(c.Expects), len(c.Rules))
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// etsGroups is the group address hierarchy of the config, as ETS imports it:
+// main and middle groups with names, and the group addresses in them.
+type etsGroups struct {
+	Addrs   []cemi.GroupAddr // sorted
+	Mains   map[int]string   // names of the main groups
+	Middles map[int]string   // names of the middle groups, by main<<3 | middle
+	Names   map[cemi.GroupAddr]string
+}
+
+func groupParts(addr cemi.GroupAddr) (main, middle, sub int) {
+	return int(addr >> 11), int(addr>>8) & 7, int(addr & 0xFF)
+}
+
+// newETSGroups derives the names of the main and middle groups from the slash-separated
+// names of the group addresses: the first part of the names in a main group (and the second
+// one in a middle group) most used is the name of the range, and is removed from the names.
+func newETSGroups(addresses map[cemi.GroupAddr]addrNameType) *etsGroups {
+	g := &etsGroups{
+		Mains:   make(map[int]string),
+		Middles: make(map[int]string),
+		Names:   make(map[cemi.GroupAddr]string),
+	}
+	mainVotes := make(map[int]map[string]int)
+	middleVotes := make(map[int]map[string]int)
+	vote := func(votes map[int]map[string]int, key int, name string) {
+		if votes[key] == nil {
+			votes[key] = make(map[string]int)
+		}
+		votes[key][name]++
+	}
+	for addr, nt := range addresses {
+		g.Addrs = append(g.Addrs, addr)
+		main, middle, _ := groupParts(addr)
+		parts := strings.Split(nt.Name, "/")
+		if len(parts) >= 2 {
+			vote(mainVotes, main, parts[0])
+		}
+		if len(parts) >= 3 {
+			vote(middleVotes, main<<3|middle, parts[1])
+		}
+	}
+	sort.Slice(g.Addrs, func(i, j int) bool { return g.Addrs[i] < g.Addrs[j] })
+	winner := func(votes map[string]int) string {
+		best := ""
+		for name, n := range votes {
+			if n > votes[best] || (n == votes[best] && name < best) {
+				best = name
+			}
+		}
+		return best
+	}
+
+	for _, addr := range g.Addrs {
+		main, middle, _ := groupParts(addr)
+		if _, ok := g.Mains[main]; !ok {
+			g.Mains[main] = winner(mainVotes[main])
+			if g.Mains[main] == "" {
+				g.Mains[main] = fmt.Sprintf("Main group %d", main)
+			}
+		}
+		if _, ok := g.Middles[main<<3|middle]; !ok {
+			g.Middles[main<<3|middle] = winner(middleVotes[main<<3|middle])
+			if g.Middles[main<<3|middle] == "" {
+				g.Middles[main<<3|middle] = fmt.Sprintf("Middle group %d/%d", main, middle)
+			}
+		}
+		name := addresses[addr].Name
+		parts := strings.Split(name, "/")
+		switch {
+		case len(parts) >= 3 && parts[0] == g.Mains[main] && parts[1] == g.Middles[main<<3|middle]:
+			name = strings.Join(parts[2:], "/")
+		case len(parts) >= 2 && parts[0] == g.Mains[main]:
+			name = strings.Join(parts[1:], "/")
+		}
+		g.Names[addr] = name
+	}
+	return g
+}
+
+// etsDPT converts a datapoint type from the format used by knx-go ("9.001") to ETS ("DPST-9-1").
+func etsDPT(dpt string) string {
+	var main, sub int
+	if n, _ := fmt.Sscanf(dpt, "%d.%d", &main, &sub); n == 2 {
+		return fmt.Sprintf("DPST-%d-%d", main, sub)
+	}
+	return ""
+}
+
+// writeETSCSV writes the group addresses in the CSV format imported by ETS
+// (with main, middle and sub groups in different columns).
+func writeETSCSV(w io.Writer, g *etsGroups, addresses map[cemi.GroupAddr]addrNameType, sep rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = sep
+	cw.Write([]string{"Main", "Middle", "Sub", "Address", "Central", "Unfiltered", "Description", "DatapointType", "Security"})
+	lastMain, lastMiddle := -1, -1
+	for _, addr := range g.Addrs {
+		main, middle, _ := groupParts(addr)
+		if main != lastMain {
+			cw.Write([]string{g.Mains[main], "", "", fmt.Sprintf("%d/-/-", main), "", "", "", "", "Auto"})
+			lastMain, lastMiddle = main, -1
+		}
+		if middle != lastMiddle {
+			cw.Write([]string{"", g.Middles[main<<3|middle], "", fmt.Sprintf("%d/%d/-", main, middle), "", "", "", "", "Auto"})
+			lastMiddle = middle
+		}
+		nt := addresses[addr]
+		cw.Write([]string{"", "", g.Names[addr], addr.String(), "", "", nt.Description, etsDPT(nt.DPT), "Auto"})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ETS group address export, as in http://knx.org/xml/ga-export/01
+type etsXMLExport struct {
+	XMLName xml.Name      `xml:"http://knx.org/xml/ga-export/01 GroupAddress-Export"`
+	Ranges  []etsXMLRange `xml:"GroupRange"`
+}
+
+type etsXMLRange struct {
+	Name       string          `xml:"Name,attr"`
+	RangeStart int             `xml:"RangeStart,attr"`
+	RangeEnd   int             `xml:"RangeEnd,attr"`
+	Ranges     []etsXMLRange   `xml:"GroupRange"`
+	Addresses  []etsXMLAddress `xml:"GroupAddress"`
+}
+
+type etsXMLAddress struct {
+	Name        string `xml:"Name,attr"`
+	Address     string `xml:"Address,attr"`
+	DPTs        string `xml:"DPTs,attr,omitempty"`
+	Description string `xml:"Description,attr,omitempty"`
+}
+
+// writeETSXML writes the group addresses in the XML format imported by ETS.
+func writeETSXML(w io.Writer, g *etsGroups, addresses map[cemi.GroupAddr]addrNameType) error {
+	var export etsXMLExport
+	lastMain, lastMiddle := -1, -1
+	for _, addr := range g.Addrs {
+		main, middle, _ := groupParts(addr)
+		if main != lastMain {
+			start := main << 11
+			if start == 0 {
+				start = 1 // 0/0/0 is not a valid group address
+			}
+			export.Ranges = append(export.Ranges, etsXMLRange{Name: g.Mains[main], RangeStart: start, RangeEnd: main<<11 | 0x7FF})
+			lastMain, lastMiddle = main, -1
+		}
+		mr := &export.Ranges[len(export.Ranges)-1]
+		if middle != lastMiddle {
+			start := main<<11 | middle<<8
+			if start == 0 {
+				start = 1
+			}
+			mr.Ranges = append(mr.Ranges, etsXMLRange{Name: g.Middles[main<<3|middle], RangeStart: start, RangeEnd: main<<11 | middle<<8 | 0xFF})
+			lastMiddle = middle
+		}
+		r := &mr.Ranges[len(mr.Ranges)-1]
+		nt := addresses[addr]
+		r.Addresses = append(r.Addresses, etsXMLAddress{
+			Name:        g.Names[addr],
+			Address:     addr.String(),
+			DPTs:        etsDPT(nt.DPT),
+			Description: nt.Description,
+		})
+	}
+	io.WriteString(w, `<?xml version="1.0" encoding="utf-8" standalone="yes"?>`+"\n")
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(export); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// exportETSCommand implements "knxweb export-ets": it writes the group addresses
+// of the config in a format which can be imported in ETS.
+func exportETSCommand(args []string) int {
+	fs := flag.NewFlagSet("export-ets", flag.ExitOnError)
+	configFile := fs.String("config", "knx.cfg", "config file")
+	format := fs.String("format", "csv", "output format: \"csv\" or \"xml\"")
+	sep := fs.String("sep", ",", "separator of CSV fields")
+	output := fs.String("o", "", "output file (default: standard output)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s export-ets [options]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 || (*format != "csv" && *format != "xml") || len([]rune(*sep)) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	c, err := ReadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	w := bufio.NewWriter(os.Stdout)
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer file.Close()
+		w = bufio.NewWriter(file)
+	}
+	g := newETSGroups(c.Addresses)
+	if *format == "xml" {
+		err = writeETSXML(w, g, c.Addresses)
+	} else {
+		err = writeETSCSV(w, g, c.Addresses, []rune(*sep)[0])
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -1,5 +1,5 @@
 module github.com/cespedes/knxweb
 
-go 1.13
+go 1.17
 
 require github.com/vapourismo/knx-go v0.0.0-20220106020224-a49bd360c13e
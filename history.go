@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// loadHistory rebuilds s.Messages and s.Values from the daily text logs
+// of the last days in config.Logdir.  Lines which cannot be parsed are skipped.
+func (s *Server) loadHistory(days int) {
+	cfg := getConfig()
+	var msgs []knxMsg
+	now := time.Now()
+	for d := days - 1; d >= 0; d-- {
+		filename := path.Join(cfg.Logdir, now.AddDate(0, 0, -d).Format("2006/0102.log"))
+		file, err := os.Open(filename)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				appLog.Warnf("%v", err)
+			}
+			continue
+		}
+		bad := 0
+		sc := bufio.NewScanner(file)
+		for sc.Scan() {
+			k, err := parseLogLine(sc.Text())
+			if err != nil {
+				bad++
+				continue
+			}
+			msgs = append(msgs, k)
+		}
+		if err := sc.Err(); err != nil {
+			appLog.Warnf("%s: %v", filename, err)
+		}
+		file.Close()
+		if bad > 0 {
+			appLog.Warnf("%s: skipped %d lines which could not be parsed", filename, bad)
+		}
+	}
+	if len(msgs) > MessagesSizeMax {
+		msgs = msgs[len(msgs)-MessagesSizeTrunc:]
+	}
+	appLog.Infof("Loaded %d messages from the logs of the last %d days", len(msgs), days)
+
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	s.Messages = append(msgs, s.Messages...)
+	for _, k := range msgs {
+		s.Stats.Add(k)
+		s.Inventory.Add(k)
+		if k.When.After(s.DevicesSeen[k.Event.Source]) {
+			s.DevicesSeen[k.Event.Source] = k.When
+		}
+		if k.Event.Command != knx.GroupRead && k.When.After(s.LastWrite[k.Event.Destination]) {
+			s.LastWrite[k.Event.Destination] = k.When
+		}
+		if s.SentTo[k.Event.Source] == nil {
+			s.SentTo[k.Event.Source] = make(map[cemi.GroupAddr]time.Time)
+		}
+		if k.When.After(s.SentTo[k.Event.Source][k.Event.Destination]) {
+			s.SentTo[k.Event.Source][k.Event.Destination] = k.When
+		}
+		if k.Event.Command != knx.GroupRead {
+			if s.WroteTo[k.Event.Source] == nil {
+				s.WroteTo[k.Event.Source] = make(map[cemi.GroupAddr]time.Time)
+			}
+			if k.When.After(s.WroteTo[k.Event.Source][k.Event.Destination]) {
+				s.WroteTo[k.Event.Source][k.Event.Destination] = k.When
+			}
+		}
+		if old, ok := s.Values[k.Event.Destination]; !ok {
+			s.SortedValues = append(s.SortedValues, k.Event.Destination)
+		} else if old.When.After(k.When) {
+			// status.json may have newer values
+			continue
+		}
+		s.Values[k.Event.Destination] = k
+	}
+	sort.Slice(s.SortedValues, func(i, j int) bool { return s.SortedValues[i] < s.SortedValues[j] })
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cespedes/knxweb/knxproj"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// etsNames is how names from an ETS project are converted to knxweb names,
+// which cannot have spaces and use "/" as separator.
+type etsNames struct {
+	Path  bool   // prefix group addresses with the names of their ranges
+	Lower bool   // convert names to lowercase
+	Sep   string // replacement for spaces
+}
+
+var defaultETSNames = etsNames{Path: true, Sep: "_"}
+
+func (n etsNames) mangle(str string) string {
+	str = strings.TrimSpace(str)
+	str = strings.ReplaceAll(str, "/", "-")
+	str = strings.Join(strings.Fields(str), n.Sep)
+	if n.Lower {
+		str = strings.ToLower(str)
+	}
+	return str
+}
+
+// group returns the knxweb name of a group address.
+func (n etsNames) group(g knxproj.Group) string {
+	var parts []string
+	if n.Path {
+		for _, r := range g.Ranges {
+			if p := n.mangle(r); p != "" {
+				parts = append(parts, p)
+			}
+		}
+	}
+	name := n.mangle(g.Name)
+	if name == "" {
+		name = g.Address.String()
+	}
+	return strings.Join(append(parts, name), "/")
+}
+
+// unique adds a number to name if it is already in used, and adds it to used.
+func (n etsNames) unique(name string, used map[string]bool) string {
+	result := name
+	for i := 2; used[result]; i++ {
+		result = fmt.Sprintf("%s%s%d", name, n.Sep, i)
+	}
+	used[result] = true
+	return result
+}
+
+// device returns the knxweb name of a device.
+func (n etsNames) device(d knxproj.Device) string {
+	if name := n.mangle(d.Name); name != "" {
+		return name
+	}
+	return d.Address.String()
+}
+
+// mainGroup returns the main group of a 3-level group address.
+func mainGroup(addr cemi.GroupAddr) int {
+	return int(addr >> 11)
+}
+
+// importETSCommand implements "knxweb import-ets": it writes device and address
+// entries for a config file from an ETS project.
+func importETSCommand(args []string) int {
+	fs := flag.NewFlagSet("import-ets", flag.ExitOnError)
+	names := fs.String("names", "path", "names of group addresses: \"path\" (with group ranges) or \"name\"")
+	lower := fs.Bool("lower", false, "convert names to lowercase")
+	sep := fs.String("sep", defaultETSNames.Sep, "replacement for spaces in names")
+	mains := fs.String("main", "", "import only these main groups (as in \"1,3\")")
+	devices := fs.Bool("devices", true, "import devices")
+	merge := fs.String("merge", "", "add only the entries not defined in this config file, and keep it unchanged")
+	format := fs.String("format", "", "output format: \"cfg\" or \"toml\" (default: as the merged file, or cfg)")
+	output := fs.String("o", "", "output file (default: standard output)")
+	password := fs.String("password", "", "password of the project, if protected (default: $"+knxproj.PasswordEnv+")")
+	passwordFile := fs.String("password-file", "", "file with the password of the project")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s import-ets [options] <file.knxproj>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if *names != "path" && *names != "name" {
+		fs.Usage()
+		return 2
+	}
+	n := etsNames{Path: *names == "path", Lower: *lower, Sep: *sep}
+
+	onlyMain := make(map[int]bool)
+	if *mains != "" {
+		for _, str := range strings.Split(*mains, ",") {
+			m, err := strconv.Atoi(strings.TrimSpace(str))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid main group %q\n", str)
+				return 2
+			}
+			onlyMain[m] = true
+		}
+	}
+	if *format == "" {
+		*format = "cfg"
+		if strings.HasSuffix(*merge, ".toml") {
+			*format = "toml"
+		}
+	}
+	if *format != "cfg" && *format != "toml" {
+		fs.Usage()
+		return 2
+	}
+
+	pw, err := knxproj.Password(*password, *passwordFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	proj, err := knxproj.Open(fs.Arg(0), pw)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+
+	// entries already in the merged file are not touched
+	var existing []byte
+	old := &Config{Devices: map[cemi.IndividualAddr]string{}, Addresses: map[cemi.GroupAddr]addrNameType{}}
+	if *merge != "" {
+		if old, err = ReadConfig(*merge); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if existing, err = os.ReadFile(*merge); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	usedNames := make(map[string]bool)
+	for _, nt := range old.Addresses {
+		usedNames[nt.Name] = true
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer file.Close()
+		w = bufio.NewWriter(file)
+	}
+	w.Write(existing)
+	if len(existing) > 0 && existing[len(existing)-1] != '\n' {
+		w.WriteString("\n")
+	}
+	fmt.Fprintf(w, "\n# imported from %s (%s) on %s\n", fs.Arg(0), proj.Name, time.Now().Format("2006-01-02 15:04"))
+
+	if *devices {
+		for _, d := range proj.Devices {
+			if _, ok := old.Devices[d.Address]; ok {
+				continue
+			}
+			writeImportEntry(w, *format, "device", [][2]string{
+				{"address", d.Address.String()},
+				{"name", n.device(d)},
+			})
+		}
+	}
+	for _, g := range proj.Groups {
+		if len(onlyMain) > 0 && !onlyMain[mainGroup(g.Address)] {
+			continue
+		}
+		if _, ok := old.Addresses[g.Address]; ok {
+			continue
+		}
+		name := n.group(g)
+		if g.DPT == "" {
+			fmt.Fprintf(w, "# %s %s: unknown DPT\n", g.Address, name)
+			continue
+		}
+		name = n.unique(name, usedNames)
+		writeImportEntry(w, *format, "address", [][2]string{
+			{"address", g.Address.String()},
+			{"dpt", g.DPT},
+			{"name", name},
+			{"description", g.Description},
+		})
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// writeImportEntry writes a device or address entry in the legacy or TOML format.
+// Empty values are omitted; the legacy format has no description.
+func writeImportEntry(w io.Writer, format string, kind string, keys [][2]string) {
+	if format == "toml" {
+		fmt.Fprintf(w, "\n[[%s]]\n", kind)
+		for _, kv := range keys {
+			if kv[1] != "" {
+				fmt.Fprintf(w, "%s = %s\n", kv[0], tomlQuote(kv[1]))
+			}
+		}
+		return
+	}
+	fields := []string{kind}
+	for _, kv := range keys {
+		if kv[0] != "description" {
+			fields = append(fields, kv[1])
+		}
+	}
+	fmt.Fprintln(w, strings.Join(fields, " "))
+}
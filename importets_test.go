@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteImportEntryTOML(t *testing.T) {
+	description := "Light \"main\"\\kitchen\n\tdimmable\a\x1b"
+	var buf bytes.Buffer
+	writeImportEntry(&buf, "toml", "device", [][2]string{{"address", "1.1.5"}, {"name", "kitchen/actuator"}})
+	writeImportEntry(&buf, "toml", "address", [][2]string{
+		{"address", "1/2/3"},
+		{"dpt", ""},
+		{"name", "kitchen/light"},
+		{"description", description},
+	})
+	doc, errs := parseTOMLText(t, buf.String())
+	if errs != nil {
+		t.Fatalf("%s\n%q", buf.String(), errs)
+	}
+	if len(doc.Tables) != 2 || doc.Tables[0].Name != "device" || doc.Tables[1].Name != "address" {
+		t.Fatalf("got tables %+v", doc.Tables)
+	}
+	addr := doc.Tables[1]
+	if _, ok := addr.Keys["dpt"]; ok {
+		t.Errorf("empty dpt was written")
+	}
+	if got, _ := addr.String("description"); got != description {
+		t.Errorf("description: got %q, want %q", got, description)
+	}
+	if got, _ := addr.String("name"); got != "kitchen/light" {
+		t.Errorf("name: got %q", got)
+	}
+}
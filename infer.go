@@ -0,0 +1,252 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cespedes/knxweb/knxproj"
+	"github.com/vapourismo/knx-go/knx/cemi"
+	"github.com/vapourismo/knx-go/knx/dpt"
+)
+
+const InferMaxSamples = 64 // Number of different payloads kept per group address
+
+// DPTCandidate is a possible DPT for a group address, with a confidence between 0 and 1.
+type DPTCandidate struct {
+	DPT        string
+	Confidence float64
+	Reason     string
+}
+
+// DPTInference contains the likely DPTs of an unconfigured group address.
+type DPTInference struct {
+	Address    string
+	Telegrams  int
+	Sizes      map[int]int // number of telegrams seen with each payload length
+	Candidates []DPTCandidate
+	ConfigLine string `json:",omitempty"` // suggested config line, using the best candidate
+}
+
+// etsDPTs returns what the ETS project knows about the type of a group address:
+// the DPTs of the group objects linked to it, and its main type.
+// Group addresses with a DPT are added to the config by loadETS, so for unconfigured
+// addresses the main type ("DPT-9" in the project) is usually all there is.
+func etsDPTs(proj *knxproj.Project, addr cemi.GroupAddr) (dpts []string, mainType int) {
+	if proj == nil {
+		return nil, 0
+	}
+	i := sort.Search(len(proj.Groups), func(i int) bool { return proj.Groups[i].Address >= addr })
+	if i == len(proj.Groups) || proj.Groups[i].Address != addr {
+		return nil, 0
+	}
+	add := func(dpt string) {
+		if dpt == "" {
+			return
+		}
+		for _, d := range dpts {
+			if d == dpt {
+				return
+			}
+		}
+		dpts = append(dpts, dpt)
+	}
+	g := proj.Groups[i]
+	add(g.DPT)
+	for _, d := range proj.Devices {
+		for _, o := range d.Objects {
+			for _, a := range o.Groups {
+				if a == addr {
+					add(o.DPT)
+				}
+			}
+		}
+	}
+	return dpts, g.MainType
+}
+
+// inferDPT proposes DPTs for a group address, from the DPTs and main type it has
+// in the ETS project (see etsDPTs), the sizes of its payloads and the distribution
+// of the values seen.
+func inferDPT(sizes map[int]int, samples [][]byte, etsTypes []string, etsMain int) []DPTCandidate {
+	var result []DPTCandidate
+	for _, t := range etsTypes {
+		if _, ok := dpt.Produce(t); ok {
+			// several DPTs mean that the linked objects do not agree
+			result = append(result, DPTCandidate{DPT: t, Confidence: math.Round(95/float64(len(etsTypes))) / 100, Reason: "linked group objects in the ETS project"})
+		}
+	}
+
+	// Use the most frequent payload length:
+	size, count, total := 0, 0, 0
+	for l, n := range sizes {
+		total += n
+		if n > count || (n == count && l < size) {
+			size, count = l, n
+		}
+	}
+	if total == 0 {
+		return result
+	}
+	var values [][]byte
+	for _, s := range samples {
+		if len(s) == size {
+			values = append(values, s)
+		}
+	}
+
+	var candidates []DPTCandidate
+	add := func(dpt string, confidence float64, reason string) {
+		candidates = append(candidates, DPTCandidate{DPT: dpt, Confidence: confidence, Reason: reason})
+	}
+	switch size {
+	case 1:
+		// values up to 6 bits are stored in the APCI byte;
+		// there are no common DPTs of 5 or 6 bits
+		if allValues(values, func(b []byte) bool { return b[0] <= 1 }) {
+			add("1.001", 0.9, "1-bit values only")
+		} else if allValues(values, func(b []byte) bool { return b[0] <= 0x0F }) {
+			add("3.007", 0.6, "4-bit values")
+			add("3.008", 0.3, "4-bit values")
+		}
+	case 2:
+		if allValues(values, func(b []byte) bool { return b[1] <= 4 }) {
+			add("20.102", 0.4, "small enumerated values")
+		}
+		if allValues(values, func(b []byte) bool { return b[1] == 0 || b[1] == 0xFF }) {
+			add("5.001", 0.7, "only 0% and 100% values")
+		} else {
+			add("5.001", 0.5, "1-byte values")
+		}
+		add("5.010", 0.3, "1-byte values")
+		add("6.010", 0.1, "1-byte values")
+	case 3:
+		floats := make([]float64, len(values))
+		for i, b := range values {
+			floats[i] = knxFloat16(b[1], b[2])
+		}
+		switch {
+		case allFloats(floats, -30, 60):
+			add("9.001", 0.7, "2-byte float values in temperature range")
+			add("9.007", 0.2, "2-byte float values")
+		case allFloats(floats, 0, 100):
+			add("9.007", 0.5, "2-byte float values in humidity range")
+		case allFloats(floats, 0, 100000):
+			add("9.004", 0.5, "2-byte float values in illuminance range")
+		}
+		add("7.001", 0.2, "2-byte values")
+	case 4:
+		isTime := allValues(values, func(b []byte) bool {
+			return b[1]&0x1F < 24 && b[2] < 60 && b[3] < 60
+		})
+		isDate := allValues(values, func(b []byte) bool {
+			day, month, year := b[1], b[2], b[3]
+			return day >= 1 && day <= 31 && month >= 1 && month <= 12 && year < 100
+		})
+		switch {
+		case isTime && !isDate:
+			add("10.001", 0.8, "valid times of day")
+		case isDate && !isTime:
+			add("11.001", 0.8, "valid dates")
+		case isTime && isDate:
+			add("10.001", 0.45, "valid times of day")
+			add("11.001", 0.45, "valid dates")
+		default:
+			add("232.600", 0.3, "3-byte values")
+		}
+	case 5:
+		if allValues(values, func(b []byte) bool {
+			f := float64(math.Float32frombits(uint32(b[1])<<24 | uint32(b[2])<<16 | uint32(b[3])<<8 | uint32(b[4])))
+			return !math.IsNaN(f) && !math.IsInf(f, 0) && (f == 0 || (math.Abs(f) > 1e-6 && math.Abs(f) < 1e9))
+		}) {
+			add("14.056", 0.5, "plausible 4-byte float values")
+			add("13.001", 0.3, "4-byte values")
+		} else {
+			add("13.001", 0.5, "4-byte values")
+			add("12.001", 0.3, "4-byte values")
+		}
+	case 9:
+		add("19.001", 0.8, "8-byte values")
+	case 15:
+		if allValues(values, func(b []byte) bool {
+			for _, c := range b[1:] {
+				if c != 0 && (c < 0x20 || c > 0x7E) {
+					return false
+				}
+			}
+			return true
+		}) {
+			add("16.000", 0.8, "ASCII strings")
+		} else {
+			add("16.001", 0.6, "ISO 8859-1 strings")
+		}
+	}
+
+	// Less confidence with few telegrams, or with several payload lengths:
+	factor := float64(count) / float64(total)
+	if count < 3 {
+		factor *= 0.7
+	}
+	for _, c := range candidates {
+		if _, ok := dpt.Produce(c.DPT); !ok || hasCandidate(result, c.DPT) {
+			continue
+		}
+		if etsMain != 0 {
+			if mainType(c.DPT) == etsMain {
+				c.Confidence = math.Max(c.Confidence, 0.8)
+				c.Reason += ", main type in the ETS project"
+			} else {
+				c.Confidence *= 0.2
+				c.Reason += ", but another main type in the ETS project"
+			}
+		}
+		c.Confidence = math.Round(c.Confidence*factor*100) / 100
+		result = append(result, c)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Confidence > result[j].Confidence })
+	return result
+}
+
+func hasCandidate(candidates []DPTCandidate, dpt string) bool {
+	for _, c := range candidates {
+		if c.DPT == dpt {
+			return true
+		}
+	}
+	return false
+}
+
+// mainType returns the main type of a DPT, as in 9 for "9.001".
+func mainType(dpt string) int {
+	n, _ := strconv.Atoi(strings.SplitN(dpt, ".", 2)[0])
+	return n
+}
+
+func allValues(values [][]byte, f func([]byte) bool) bool {
+	for _, v := range values {
+		if !f(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func allFloats(values []float64, min, max float64) bool {
+	for _, v := range values {
+		if v < min || v > max {
+			return false
+		}
+	}
+	return true
+}
+
+// knxFloat16 decodes a KNX 2-byte float (DPT 9.xxx).
+func knxFloat16(hi, lo byte) float64 {
+	m := int(hi&0x07)<<8 | int(lo)
+	if hi&0x80 != 0 {
+		m -= 2048
+	}
+	e := (hi >> 3) & 0x0F
+	return 0.01 * float64(m) * float64(int(1)<<e)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/cespedes/knxweb/knxproj"
+	"github.com/vapourismo/knx-go/knx/cemi"
+	"github.com/vapourismo/knx-go/knx/dpt"
+)
+
+// float16 encodes a KNX 2-byte float (DPT 9.xxx) in a 3-byte payload.
+func float16(v float64) []byte {
+	m := int(math.Round(v * 100))
+	e := 0
+	for m < -2048 || m > 2047 {
+		m /= 2
+		e++
+	}
+	u := uint16(m) & 0x07FF
+	if m < 0 {
+		u |= 0x8000
+	}
+	u |= uint16(e) << 11
+	return []byte{0, byte(u >> 8), byte(u)}
+}
+
+// float32Payload encodes a 4-byte float (DPT 14.xxx) in a 5-byte payload.
+func float32Payload(v float32) []byte {
+	b := make([]byte, 5)
+	binary.BigEndian.PutUint32(b[1:], math.Float32bits(v))
+	return b
+}
+
+func TestKNXFloat16(t *testing.T) {
+	for _, v := range []float64{0, 21.5, -10.5, 75, 5000, -273, 200000} {
+		b := float16(v)
+		if got := knxFloat16(b[1], b[2]); math.Abs(got-v) > math.Abs(v)/1000+0.01 {
+			t.Errorf("%v: encoded as % x, decoded as %v", v, b, got)
+		}
+	}
+}
+
+func TestInferDPT(t *testing.T) {
+	tests := []struct {
+		name     string
+		samples  [][]byte
+		sizes    map[int]int // default: 3 telegrams of each sample
+		etsTypes []string
+		etsMain  int
+		want     []DPTCandidate // only DPT and Confidence are checked
+	}{
+		{
+			name:    "1 bit",
+			samples: [][]byte{{0}, {1}},
+			want:    []DPTCandidate{{DPT: "1.001", Confidence: 0.9}},
+		},
+		{
+			name:    "4 bits",
+			samples: [][]byte{{0x09}, {0x01}, {0x0B}},
+			want:    []DPTCandidate{{DPT: "3.007", Confidence: 0.6}, {DPT: "3.008", Confidence: 0.3}},
+		},
+		{
+			name:    "6 bits",
+			samples: [][]byte{{0x2A}, {0x01}},
+			want:    nil,
+		},
+		{
+			name:    "0% and 100%",
+			samples: [][]byte{{0, 0}, {0, 0xFF}},
+			want:    []DPTCandidate{{DPT: "5.001", Confidence: 0.7}, {DPT: "5.010", Confidence: 0.3}, {DPT: "6.010", Confidence: 0.1}},
+		},
+		{
+			name:    "small 1-byte values",
+			samples: [][]byte{{0, 1}, {0, 3}, {0, 2}},
+			want: []DPTCandidate{{DPT: "5.001", Confidence: 0.5}, {DPT: "20.102", Confidence: 0.4},
+				{DPT: "5.010", Confidence: 0.3}, {DPT: "6.010", Confidence: 0.1}},
+		},
+		{
+			name:    "temperatures",
+			samples: [][]byte{float16(21.5), float16(-10.5), float16(30)},
+			want:    []DPTCandidate{{DPT: "9.001", Confidence: 0.7}, {DPT: "9.007", Confidence: 0.2}, {DPT: "7.001", Confidence: 0.2}},
+		},
+		{
+			name:    "humidities",
+			samples: [][]byte{float16(75), float16(80), float16(62.5)},
+			want:    []DPTCandidate{{DPT: "9.007", Confidence: 0.5}, {DPT: "7.001", Confidence: 0.2}},
+		},
+		{
+			name:    "illuminances",
+			samples: [][]byte{float16(5000), float16(120), float16(800)},
+			want:    []DPTCandidate{{DPT: "9.004", Confidence: 0.5}, {DPT: "7.001", Confidence: 0.2}},
+		},
+		{
+			name:    "big 2-byte floats",
+			samples: [][]byte{float16(200000), float16(20)},
+			want:    []DPTCandidate{{DPT: "7.001", Confidence: 0.2}},
+		},
+		{
+			name:    "times",
+			samples: [][]byte{{0, 10, 30, 0}, {0, 0x40 | 23, 59, 59}},
+			want:    []DPTCandidate{{DPT: "10.001", Confidence: 0.8}},
+		},
+		{
+			name:    "dates",
+			samples: [][]byte{{0, 24, 12, 26}, {0, 31, 1, 27}},
+			want:    []DPTCandidate{{DPT: "11.001", Confidence: 0.8}},
+		},
+		{
+			name:    "4-byte floats",
+			samples: [][]byte{float32Payload(21.5), float32Payload(-0.25), float32Payload(0)},
+			want:    []DPTCandidate{{DPT: "14.056", Confidence: 0.5}, {DPT: "13.001", Confidence: 0.3}},
+		},
+		{
+			name:    "few telegrams",
+			samples: [][]byte{{1}},
+			sizes:   map[int]int{1: 1},
+			want:    []DPTCandidate{{DPT: "1.001", Confidence: 0.63}},
+		},
+		{
+			name:    "several lengths",
+			samples: [][]byte{{1}, float16(20)},
+			sizes:   map[int]int{1: 4, 3: 1},
+			want:    []DPTCandidate{{DPT: "1.001", Confidence: 0.72}},
+		},
+		{
+			name:     "ETS only",
+			etsTypes: []string{"9.001"},
+			want:     []DPTCandidate{{DPT: "9.001", Confidence: 0.95}},
+		},
+		{
+			name:     "ETS objects which do not agree",
+			etsTypes: []string{"1.001", "1.008"},
+			want:     []DPTCandidate{{DPT: "1.001", Confidence: 0.48}, {DPT: "1.008", Confidence: 0.48}},
+		},
+		{
+			name:     "ETS and values",
+			samples:  [][]byte{float16(21.5), float16(-10.5), float16(30)},
+			etsTypes: []string{"9.001"},
+			want:     []DPTCandidate{{DPT: "9.001", Confidence: 0.95}, {DPT: "9.007", Confidence: 0.2}, {DPT: "7.001", Confidence: 0.2}},
+		},
+		{
+			name:    "ETS main type",
+			samples: [][]byte{float16(75), float16(80), float16(62.5)},
+			etsMain: 9,
+			want:    []DPTCandidate{{DPT: "9.007", Confidence: 0.8}, {DPT: "7.001", Confidence: 0.04}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sizes := tt.sizes
+			if sizes == nil {
+				sizes = make(map[int]int)
+				for _, s := range tt.samples {
+					sizes[len(s)] += 3
+				}
+			}
+			// DPTs which knx-go cannot decode are never proposed
+			var want []DPTCandidate
+			for _, c := range tt.want {
+				if _, ok := dpt.Produce(c.DPT); ok {
+					want = append(want, c)
+				}
+			}
+			got := inferDPT(sizes, tt.samples, tt.etsTypes, tt.etsMain)
+			if len(got) != len(want) {
+				t.Fatalf("got %+v, want %+v", got, want)
+			}
+			for i := range got {
+				if got[i].DPT != want[i].DPT || got[i].Confidence != want[i].Confidence {
+					t.Errorf("candidate %d: got %s %v (%s), want %s %v", i,
+						got[i].DPT, got[i].Confidence, got[i].Reason, want[i].DPT, want[i].Confidence)
+				}
+			}
+		})
+	}
+}
+
+func TestETSDPTs(t *testing.T) {
+	group := cemi.GroupAddr(0x0A01)
+	proj := &knxproj.Project{
+		Devices: []knxproj.Device{
+			{Address: 0x1101, Objects: []knxproj.Object{{DPT: "9.001", Groups: []cemi.GroupAddr{group}}}},
+			{Address: 0x1102, Objects: []knxproj.Object{
+				{DPT: "9.007", Groups: []cemi.GroupAddr{0x0A00, group}},
+				{DPT: "1.001", Groups: []cemi.GroupAddr{0x0A00}},
+			}},
+			{Address: 0x1103, Objects: []knxproj.Object{{Groups: []cemi.GroupAddr{group}}}},
+		},
+		Groups: []knxproj.Group{{Address: 0x0A00}, {Address: group, MainType: 9}},
+	}
+	dpts, main := etsDPTs(proj, group)
+	if len(dpts) != 2 || dpts[0] != "9.001" || dpts[1] != "9.007" || main != 9 {
+		t.Errorf("got %q, main type %d", dpts, main)
+	}
+	if dpts, main := etsDPTs(proj, 0x0A02); dpts != nil || main != 0 {
+		t.Errorf("unknown group address: got %q, main type %d", dpts, main)
+	}
+	if dpts, main := etsDPTs(nil, group); dpts != nil || main != 0 {
+		t.Errorf("no ETS project: got %q, main type %d", dpts, main)
+	}
+}
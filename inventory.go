@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// InventoryEntry describes an individual or group address seen on the bus
+// but missing from the config file.
+type InventoryEntry struct {
+	Address   string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Count     int
+	DPT       string `json:",omitempty"` // Guessed DPT (only for group addresses)
+
+	sizes   map[int]int // number of telegrams with each payload length
+	samples [][]byte    // different payloads seen
+}
+
+// Inventory keeps track of devices and group addresses which are not in the config file.
+type Inventory struct {
+	mutex   sync.Mutex
+	devices map[cemi.IndividualAddr]*InventoryEntry
+	groups  map[cemi.GroupAddr]*InventoryEntry
+}
+
+// Add records a new telegram, if its source or destination are not in the config.
+func (inv *Inventory) Add(k knxMsg) {
+	cfg := getConfig()
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+
+	if inv.devices == nil {
+		inv.devices = make(map[cemi.IndividualAddr]*InventoryEntry)
+		inv.groups = make(map[cemi.GroupAddr]*InventoryEntry)
+	}
+	if _, ok := cfg.Devices[k.Event.Source]; !ok {
+		e, ok := inv.devices[k.Event.Source]
+		if !ok {
+			e = &InventoryEntry{Address: k.Event.Source.String(), FirstSeen: k.When}
+			inv.devices[k.Event.Source] = e
+		}
+		e.LastSeen = k.When
+		e.Count++
+	}
+	if _, ok := cfg.Addresses[k.Event.Destination]; !ok {
+		e, ok := inv.groups[k.Event.Destination]
+		if !ok {
+			e = &InventoryEntry{Address: k.Event.Destination.String(), FirstSeen: k.When}
+			inv.groups[k.Event.Destination] = e
+		}
+		e.LastSeen = k.When
+		e.Count++
+		// a GroupRead has no payload to infer anything from
+		if len(k.Event.Data) > 0 {
+			if e.sizes == nil {
+				e.sizes = make(map[int]int)
+			}
+			e.sizes[len(k.Event.Data)]++
+			if len(e.samples) < InferMaxSamples && !hasSample(e.samples, k.Event.Data) {
+				e.samples = append(e.samples, append([]byte(nil), k.Event.Data...))
+			}
+		}
+	}
+}
+
+func hasSample(samples [][]byte, data []byte) bool {
+	for _, s := range samples {
+		if bytes.Equal(s, data) {
+			return true
+		}
+	}
+	return false
+}
+
+// Devices returns the list of unknown devices, sorted by address.
+func (inv *Inventory) Devices() []InventoryEntry {
+	cfg := getConfig()
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+
+	var addrs []cemi.IndividualAddr
+	for addr := range inv.devices {
+		if _, ok := cfg.Devices[addr]; !ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	var result []InventoryEntry
+	for _, addr := range addrs {
+		result = append(result, *inv.devices[addr])
+	}
+	return result
+}
+
+// Groups returns the list of unknown group addresses, sorted by address.
+func (inv *Inventory) Groups() []InventoryEntry {
+	cfg := getConfig()
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+
+	var addrs []cemi.GroupAddr
+	for addr := range inv.groups {
+		if _, ok := cfg.Addresses[addr]; !ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	var result []InventoryEntry
+	for _, addr := range addrs {
+		e := *inv.groups[addr]
+		etsTypes, etsMain := etsDPTs(cfg.ETS, addr)
+		if c := inferDPT(e.sizes, e.samples, etsTypes, etsMain); len(c) > 0 {
+			e.DPT = c[0].DPT
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+// Inferences returns the likely DPTs of every unknown group address, sorted by address.
+func (inv *Inventory) Inferences() []DPTInference {
+	cfg := getConfig()
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+
+	var addrs []cemi.GroupAddr
+	for addr := range inv.groups {
+		if _, ok := cfg.Addresses[addr]; !ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	var result []DPTInference
+	for _, addr := range addrs {
+		e := inv.groups[addr]
+		etsTypes, etsMain := etsDPTs(cfg.ETS, addr)
+		di := DPTInference{
+			Address:    e.Address,
+			Telegrams:  e.Count,
+			Sizes:      make(map[int]int),
+			Candidates: inferDPT(e.sizes, e.samples, etsTypes, etsMain),
+		}
+		for l, n := range e.sizes {
+			di.Sizes[l] = n
+		}
+		if len(di.Candidates) > 0 {
+			di.ConfigLine = "address " + e.Address + " " + di.Candidates[0].DPT + " unknown/" + strings.ReplaceAll(e.Address, "/", "-")
+		}
+		result = append(result, di)
+	}
+	return result
+}
+
+// ConfigLines returns suggested "device" and "address" lines for the config file.
+func (inv *Inventory) ConfigLines() []string {
+	var lines []string
+	for _, e := range inv.Devices() {
+		lines = append(lines, "device "+e.Address+" unknown."+strings.ReplaceAll(e.Address, ".", "-"))
+	}
+	for _, e := range inv.Groups() {
+		dpt := e.DPT
+		if dpt == "" {
+			dpt = "unknown"
+		}
+		lines = append(lines, "address "+e.Address+" "+dpt+" unknown/"+strings.ReplaceAll(e.Address, "/", "-"))
+	}
+	return lines
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// jsonLogRecord is a line in the JSON Lines log.
+type jsonLogRecord struct {
+	Time        time.Time
+	Gateway     string
+	Command     string
+	Source      string
+	Device      string `json:",omitempty"`
+	Destination string
+	Name        string      `json:",omitempty"`
+	DPT         string      `json:",omitempty"`
+	Data        string      // hex
+	Value       interface{} `json:",omitempty"`
+	Text        string      `json:",omitempty"`
+	Unit        string      `json:",omitempty"`
+}
+
+func newJSONLogRecord(k knxMsg) jsonLogRecord {
+	cfg := getConfig()
+	rec := jsonLogRecord{
+		Time:        k.When,
+		Gateway:     k.Where,
+		Command:     commandName(k.Event.Command),
+		Source:      k.Event.Source.String(),
+		Device:      cfg.Devices[k.Event.Source],
+		Destination: k.Event.Destination.String(),
+		Data:        hex.EncodeToString(k.Event.Data),
+	}
+	nt, ok := cfg.Addresses[k.Event.Destination]
+	if ok {
+		rec.Name = nt.Name
+		rec.DPT = nt.DPT
+	}
+	if len(k.Event.Data) > 0 {
+		if dp, err := decodeMsg(k); err == nil {
+			if v, err := GetDPT(dp); err == nil {
+				rec.Value = v
+			}
+			rec.Text = fmt.Sprint(dp)
+			if u, ok := dp.(interface{ Unit() string }); ok {
+				rec.Unit = u.Unit()
+			}
+		}
+	}
+	if nt.Unit != "" {
+		rec.Unit = nt.Unit
+	}
+	return rec
+}
+
+// knxMsg converts a record back to a telegram.
+func (rec jsonLogRecord) knxMsg() (knxMsg, error) {
+	var k knxMsg
+	var err error
+	k.When = rec.Time
+	k.Where = rec.Gateway
+	switch rec.Command {
+	case "read":
+		k.Event.Command = knx.GroupRead
+	case "response":
+		k.Event.Command = knx.GroupResponse
+	case "write":
+		k.Event.Command = knx.GroupWrite
+	default:
+		return k, fmt.Errorf("unknown command %q", rec.Command)
+	}
+	if k.Event.Source, err = cemi.NewIndividualAddrString(rec.Source); err != nil {
+		return k, err
+	}
+	if k.Event.Destination, err = cemi.NewGroupAddrString(rec.Destination); err != nil {
+		return k, err
+	}
+	if k.Event.Data, err = hex.DecodeString(rec.Data); err != nil {
+		return k, err
+	}
+	return k, nil
+}
+
+// jsonLogName returns the name of the JSON log file for a day and index.
+func jsonLogName(t time.Time, index int) string {
+	cfg := getConfig()
+	if index == 0 {
+		return path.Join(cfg.Logdir, t.Format("2006/0102.jsonl"))
+	}
+	return path.Join(cfg.Logdir, t.Format("2006/0102")+fmt.Sprintf(".%d.jsonl", index))
+}
+
+// LogJSON writes a telegram to the JSON Lines log in config.Logdir.
+// There is one file per day, rotated when it grows bigger than config.LogMaxSize.
+// s.logMutex must be held.
+func (s *Server) LogJSON(k knxMsg) {
+	cfg := getConfig()
+	day := k.When.Format("20060102")
+	if s.jsonLogFile != nil && (day != s.jsonLogDay || (cfg.LogMaxSize > 0 && s.jsonLogSize >= cfg.LogMaxSize)) {
+		s.closeJSONLog(cfg.LogCompress)
+		if day == s.jsonLogDay {
+			s.jsonLogIndex++
+		}
+	}
+	if s.jsonLogFile == nil {
+		if day != s.jsonLogDay {
+			s.jsonLogDay = day
+			s.jsonLogIndex = 0
+			go cleanJSONLogs()
+		}
+		if err := s.openJSONLog(k.When); err != nil {
+			appLog.Errorf("JSON log: %v", err)
+			return
+		}
+	}
+	b, err := json.Marshal(newJSONLogRecord(k))
+	if err != nil {
+		appLog.Errorf("JSON log: %v", err)
+		return
+	}
+	n, err := s.jsonLogFile.Write(append(b, '\n'))
+	s.jsonLogSize += int64(n)
+	if err != nil {
+		appLog.Errorf("JSON log: %v", err)
+		// the same file is opened again with the next telegram,
+		// so it must not be compressed
+		s.closeJSONLog(false)
+	}
+}
+
+// openJSONLog opens the first file for that day which is not compressed nor full.
+func (s *Server) openJSONLog(t time.Time) error {
+	cfg := getConfig()
+	for ; ; s.jsonLogIndex++ {
+		filename := jsonLogName(t, s.jsonLogIndex)
+		if _, err := os.Stat(filename + ".gz"); err == nil {
+			continue
+		}
+		fi, err := os.Stat(filename)
+		if err == nil && cfg.LogMaxSize > 0 && fi.Size() >= cfg.LogMaxSize {
+			if cfg.LogCompress {
+				go compressFile(filename)
+			}
+			continue
+		}
+		os.MkdirAll(filepath.Dir(filename), 0777)
+		s.jsonLogFile, err = os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+		if err != nil {
+			return err
+		}
+		s.jsonLogSize = 0
+		if fi != nil {
+			s.jsonLogSize = fi.Size()
+		}
+		return nil
+	}
+}
+
+// closeJSONLog closes the current file, compressing it if it is not going to be reopened.
+func (s *Server) closeJSONLog(compress bool) {
+	filename := s.jsonLogFile.Name()
+	if err := s.jsonLogFile.Close(); err != nil {
+		appLog.Errorf("JSON log: %v", err)
+	}
+	s.jsonLogFile = nil
+	if compress {
+		go compressFile(filename)
+	}
+}
+
+// compressFile replaces a file with a gzipped version of it.
+func compressFile(filename string) {
+	err := func() error {
+		in, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.Create(filename + ".gz.tmp")
+		if err != nil {
+			return err
+		}
+		zw := gzip.NewWriter(out)
+		if _, err := io.Copy(zw, in); err != nil {
+			out.Close()
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+		if err := os.Rename(filename+".gz.tmp", filename+".gz"); err != nil {
+			return err
+		}
+		return os.Remove(filename)
+	}()
+	if err != nil {
+		appLog.Errorf("compressing %s: %v", filename, err)
+		os.Remove(filename + ".gz.tmp")
+	}
+}
+
+// cleanJSONLogs removes the JSON logs older than config.LogRetention days.
+func cleanJSONLogs() {
+	cfg := getConfig()
+	if cfg.LogRetention <= 0 {
+		return
+	}
+	limit := time.Now().AddDate(0, 0, -cfg.LogRetention)
+	filepath.Walk(cfg.Logdir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		if !strings.HasSuffix(p, ".jsonl") && !strings.HasSuffix(p, ".jsonl.gz") {
+			return nil
+		}
+		if info.ModTime().Before(limit) {
+			if err := os.Remove(p); err != nil {
+				appLog.Errorf("removing old JSON log: %v", err)
+			}
+		}
+		return nil
+	})
+}
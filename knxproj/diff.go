@@ -0,0 +1,140 @@
+package knxproj
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Change is a difference between two versions of a project.
+type Change struct {
+	Kind    string // "added", "removed", "renamed", "dpt", "description", "links" or "flags"
+	What    string // "group", "device" or "object"
+	Address string // of the group address or device
+	Object  string `json:",omitempty"` // name of the group object, for objects
+	Old     string `json:",omitempty"` // what changed, in the old version
+	New     string `json:",omitempty"` // and in the new one
+}
+
+func (c Change) String() string {
+	what := c.What + " " + c.Address
+	if c.Object != "" {
+		what += " " + c.Object
+	}
+	switch c.Kind {
+	case "added":
+		return fmt.Sprintf("%s added: %s", what, c.New)
+	case "removed":
+		return fmt.Sprintf("%s removed: %s", what, c.Old)
+	}
+	return fmt.Sprintf("%s %s: %s -> %s", what, c.Kind, c.Old, c.New)
+}
+
+// Diff returns the group addresses, devices and group objects added, removed or changed
+// between two versions of a project.
+func Diff(old, new *Project) []Change {
+	var changes []Change
+	add := func(kind, what, addr, object, o, n string) {
+		changes = append(changes, Change{Kind: kind, What: what, Address: addr, Object: object, Old: o, New: n})
+	}
+
+	oldGroups := make(map[string]Group)
+	for _, g := range old.Groups {
+		oldGroups[g.Address.String()] = g
+	}
+	newGroups := make(map[string]bool)
+	for _, g := range new.Groups {
+		addr := g.Address.String()
+		newGroups[addr] = true
+		o, ok := oldGroups[addr]
+		if !ok {
+			add("added", "group", addr, "", "", groupPath(g))
+			continue
+		}
+		if groupPath(o) != groupPath(g) {
+			add("renamed", "group", addr, "", groupPath(o), groupPath(g))
+		}
+		if o.DPT != g.DPT {
+			add("dpt", "group", addr, "", o.DPT, g.DPT)
+		}
+		if o.Description != g.Description {
+			add("description", "group", addr, "", o.Description, g.Description)
+		}
+	}
+	for _, g := range old.Groups {
+		if !newGroups[g.Address.String()] {
+			add("removed", "group", g.Address.String(), "", groupPath(g), "")
+		}
+	}
+
+	oldDevices := make(map[string]Device)
+	for _, d := range old.Devices {
+		oldDevices[d.Address.String()] = d
+	}
+	newDevices := make(map[string]bool)
+	for _, d := range new.Devices {
+		addr := d.Address.String()
+		newDevices[addr] = true
+		o, ok := oldDevices[addr]
+		if !ok {
+			add("added", "device", addr, "", "", d.Name)
+			continue
+		}
+		if o.Name != d.Name {
+			add("renamed", "device", addr, "", o.Name, d.Name)
+		}
+		changes = append(changes, diffObjects(addr, o.Objects, d.Objects)...)
+	}
+	for _, d := range old.Devices {
+		if !newDevices[d.Address.String()] {
+			add("removed", "device", d.Address.String(), "", d.Name, "")
+		}
+	}
+	return changes
+}
+
+// diffObjects returns the changes in the group objects of a device.
+// Only objects linked to some group address are in a Project.
+func diffObjects(addr string, old, new []Object) []Change {
+	var changes []Change
+	oldObjects := make(map[string]Object)
+	for _, o := range old {
+		oldObjects[o.RefID] = o
+	}
+	newObjects := make(map[string]bool)
+	for _, n := range new {
+		newObjects[n.RefID] = true
+		o, ok := oldObjects[n.RefID]
+		if !ok {
+			changes = append(changes, Change{Kind: "added", What: "object", Address: addr, Object: n.Name, New: linksString(n)})
+			continue
+		}
+		if linksString(o) != linksString(n) {
+			changes = append(changes, Change{Kind: "links", What: "object", Address: addr, Object: n.Name, Old: linksString(o), New: linksString(n)})
+		}
+		if o.Flags != n.Flags {
+			changes = append(changes, Change{Kind: "flags", What: "object", Address: addr, Object: n.Name, Old: o.Flags, New: n.Flags})
+		}
+		if o.DPT != n.DPT {
+			changes = append(changes, Change{Kind: "dpt", What: "object", Address: addr, Object: n.Name, Old: o.DPT, New: n.DPT})
+		}
+	}
+	for _, o := range old {
+		if !newObjects[o.RefID] {
+			changes = append(changes, Change{Kind: "removed", What: "object", Address: addr, Object: o.Name, Old: linksString(o)})
+		}
+	}
+	return changes
+}
+
+// groupPath returns the name of a group address with its ranges, as in "Lights/Kitchen/Ceiling".
+func groupPath(g Group) string {
+	return strings.Join(append(append([]string(nil), g.Ranges...), g.Name), "/")
+}
+
+// linksString returns the group addresses linked to an object, sorted.
+func linksString(o Object) string {
+	groups := groupStrings(o.Groups)
+	sort.Strings(groups)
+	return strings.Join(groups, " ")
+}
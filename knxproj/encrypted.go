@@ -0,0 +1,284 @@
+package knxproj
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/cespedes/knxweb/ets"
+)
+
+var (
+	ErrPasswordRequired = errors.New("project is password-protected; a password is needed")
+	ErrWrongPassword    = errors.New("wrong password")
+)
+
+// PasswordEnv is the environment variable with the password of protected projects.
+const PasswordEnv = "KNXPROJ_PASSWORD"
+
+// Password returns the password of a project: the one given, or the contents of a file,
+// or the value of $KNXPROJ_PASSWORD, whichever is not empty first.
+func Password(password, file string) (string, error) {
+	if password != "" {
+		return password, nil
+	}
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+	return os.Getenv(PasswordEnv), nil
+}
+
+var protectedProject = regexp.MustCompile(`^(P-[0-9A-Za-z]+)\.zip$`)
+
+// OpenArchive opens an ETS project export, which may be password-protected.
+//
+// Protected projects have their files in an inner zip file ("P-xxxx.zip") encrypted
+// with WinZip AES.  They are decrypted into an archive in memory with the layout of
+// unprotected ones, which is then read by package ets.
+func OpenArchive(filename, password string) (*ets.ExportArchive, error) {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	protected := false
+	for _, f := range r.File {
+		if protectedProject.MatchString(f.Name) {
+			protected = true
+		}
+	}
+	if !protected {
+		return ets.OpenExportArchive(filename)
+	}
+	if password == "" {
+		return nil, ErrPasswordRequired
+	}
+
+	var buf bytes.Buffer
+	if err := decryptArchive(&r.Reader, password, &buf); err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		return nil, err
+	}
+	return ets.NewExportArchive(zr), nil
+}
+
+// decryptArchive writes an archive with the files of r, where the protected projects
+// are replaced by their decrypted contents.
+func decryptArchive(r *zip.Reader, password string, out io.Writer) error {
+	// ETS6 encrypts with a password derived from the one given, which is slow to compute
+	passwords := []string{password, ets6Password(password)}
+	w := zip.NewWriter(out)
+	for _, f := range r.File {
+		m := protectedProject.FindStringSubmatch(f.Name)
+		if m == nil {
+			if err := copyRaw(w, f); err != nil {
+				return err
+			}
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		inner, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		for _, pf := range inner.File {
+			data, err := readEncrypted(pf, passwords)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", f.Name, pf.Name, err)
+			}
+			fw, err := w.Create(m[1] + "/" + pf.Name)
+			if err != nil {
+				return err
+			}
+			if _, err := fw.Write(data); err != nil {
+				return err
+			}
+		}
+	}
+	return w.Close()
+}
+
+// copyRaw copies a file from an archive to another one, without recompressing it.
+func copyRaw(w *zip.Writer, f *zip.File) error {
+	raw, err := f.OpenRaw()
+	if err != nil {
+		return err
+	}
+	fh := f.FileHeader
+	fw, err := w.CreateRaw(&fh)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, raw)
+	return err
+}
+
+// Constants of the WinZip AES encryption (https://www.winzip.com/en/support/aes-encryption/).
+const (
+	winzipAESMethod   = 99
+	winzipAESExtraID  = 0x9901
+	winzipIterations  = 1000
+	winzipAuthCodeLen = 10
+)
+
+// readEncrypted returns the uncompressed contents of a file encrypted with WinZip AES
+// (or of a file which is not encrypted), trying each of the passwords.
+func readEncrypted(f *zip.File, passwords []string) ([]byte, error) {
+	if f.Method != winzipAESMethod {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+
+	strength, method, err := winzipExtra(f.Extra)
+	if err != nil {
+		return nil, err
+	}
+	keyLen := 8 * (strength + 1) // 16, 24 or 32 bytes
+	saltLen := keyLen / 2
+	raw, err := f.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < saltLen+2+winzipAuthCodeLen {
+		return nil, errors.New("encrypted data too short")
+	}
+	salt, verifier := b[:saltLen], b[saltLen:saltLen+2]
+	data, authCode := b[saltLen+2:len(b)-winzipAuthCodeLen], b[len(b)-winzipAuthCodeLen:]
+
+	var keys []byte
+	for _, p := range passwords {
+		k := pbkdf2(sha1.New, []byte(p), salt, winzipIterations, 2*keyLen+2)
+		if bytes.Equal(k[2*keyLen:], verifier) {
+			keys = k
+			break
+		}
+	}
+	if keys == nil {
+		return nil, ErrWrongPassword
+	}
+	mac := hmac.New(sha1.New, keys[keyLen:2*keyLen])
+	mac.Write(data)
+	if !hmac.Equal(mac.Sum(nil)[:winzipAuthCodeLen], authCode) {
+		return nil, errors.New("authentication failed: corrupt file")
+	}
+	block, err := aes.NewCipher(keys[:keyLen])
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(data))
+	var counter, stream [aes.BlockSize]byte
+	for i := 0; i < len(data); i += aes.BlockSize {
+		// the counter is little-endian and starts at 1
+		for j := range counter {
+			counter[j]++
+			if counter[j] != 0 {
+				break
+			}
+		}
+		block.Encrypt(stream[:], counter[:])
+		for j := i; j < len(data) && j < i+aes.BlockSize; j++ {
+			plain[j] = data[j] ^ stream[j-i]
+		}
+	}
+
+	switch method {
+	case zip.Store:
+		return plain, nil
+	case zip.Deflate:
+		return io.ReadAll(flate.NewReader(bytes.NewReader(plain)))
+	}
+	return nil, fmt.Errorf("unsupported compression method %d", method)
+}
+
+// winzipExtra returns the AES strength (1, 2 or 3) and the actual compression method
+// from the extra field of an encrypted file.
+func winzipExtra(extra []byte) (int, uint16, error) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			break
+		}
+		if id == winzipAESExtraID && size >= 7 {
+			field := extra[4 : 4+size]
+			strength := int(field[4])
+			if strength < 1 || strength > 3 {
+				return 0, 0, fmt.Errorf("invalid AES strength %d", strength)
+			}
+			return strength, binary.LittleEndian.Uint16(field[5:]), nil
+		}
+		extra = extra[4+size:]
+	}
+	return 0, 0, errors.New("unsupported encryption")
+}
+
+// ets6Password returns the password used by ETS6 for the inner zip file,
+// derived from the one given by the user.
+func ets6Password(password string) string {
+	var utf16le []byte
+	for _, c := range utf16.Encode([]rune(password)) {
+		utf16le = append(utf16le, byte(c), byte(c>>8))
+	}
+	key := pbkdf2(sha256.New, utf16le, []byte("21.project.ets.knx.org"), 65536, 32)
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// pbkdf2 derives a key from a password, as in RFC 8018.
+func pbkdf2(h func() hash.Hash, password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(h, password)
+	var dk []byte
+	for block := uint32(1); len(dk) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.Write(prf, binary.BigEndian, block)
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for n := 1; n < iter; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range t {
+				t[i] ^= u[i]
+			}
+		}
+		dk = append(dk, t...)
+	}
+	return dk[:keyLen]
+}
@@ -0,0 +1,151 @@
+package knxproj
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testPassword = "pässwörd"
+
+var testProjectFiles = map[string]string{
+	"project.xml": `<KNX><Project Id="P-0123"><ProjectInformation Name="Home"/></Project></KNX>`,
+	"0.xml":       `<KNX><Project Id="P-0123"><Installations><Installation Name=""/></Installations></Project></KNX>`,
+}
+
+// winzipEncrypt encrypts data with WinZip AES-256, without compressing it.
+func winzipEncrypt(password string, data []byte) []byte {
+	const keyLen = 32
+	salt := make([]byte, keyLen/2)
+	for i := range salt {
+		salt[i] = byte(i)
+	}
+	keys := pbkdf2(sha1.New, []byte(password), salt, winzipIterations, 2*keyLen+2)
+	block, err := aes.NewCipher(keys[:keyLen])
+	if err != nil {
+		panic(err)
+	}
+	enc := make([]byte, len(data))
+	var counter, stream [aes.BlockSize]byte
+	for i := 0; i < len(data); i += aes.BlockSize {
+		for j := range counter {
+			counter[j]++
+			if counter[j] != 0 {
+				break
+			}
+		}
+		block.Encrypt(stream[:], counter[:])
+		for j := i; j < len(data) && j < i+aes.BlockSize; j++ {
+			enc[j] = data[j] ^ stream[j-i]
+		}
+	}
+	mac := hmac.New(sha1.New, keys[keyLen:2*keyLen])
+	mac.Write(enc)
+
+	out := append(salt, keys[2*keyLen:]...)
+	out = append(out, enc...)
+	return append(out, mac.Sum(nil)[:winzipAuthCodeLen]...)
+}
+
+// writeProtected writes a protected project, with its files encrypted with password,
+// and returns the name of the file.
+func writeProtected(t *testing.T, password string) string {
+	var inner bytes.Buffer
+	w := zip.NewWriter(&inner)
+	for name, content := range testProjectFiles {
+		enc := winzipEncrypt(password, []byte(content))
+		// AE-2, vendor "AE", AES-256, stored
+		extra := []byte{0, 0, 7, 0, 2, 0, 'A', 'E', 3, 0, 0}
+		binary.LittleEndian.PutUint16(extra, winzipAESExtraID)
+		fw, err := w.CreateRaw(&zip.FileHeader{
+			Name:               name,
+			Method:             winzipAESMethod,
+			Flags:              0x1, // encrypted
+			Extra:              extra,
+			CompressedSize64:   uint64(len(enc)),
+			UncompressedSize64: uint64(len(content)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(enc)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "test.knxproj")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w = zip.NewWriter(f)
+	fw, err := w.Create("knx_master.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(`<KNX/>`))
+	fw, err = w.Create("P-0123.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(inner.Bytes())
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestOpenProtected(t *testing.T) {
+	tests := []struct {
+		name      string
+		encrypted string // password the files are encrypted with
+		password  string // password given to OpenArchive
+		err       error
+	}{
+		{"password", testPassword, testPassword, nil},
+		{"ETS6", ets6Password(testPassword), testPassword, nil},
+		{"wrong password", testPassword, "wrong", ErrWrongPassword},
+		{"no password", testPassword, "", ErrPasswordRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := OpenArchive(writeProtected(t, tt.encrypted), tt.password)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got error %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer archive.Close()
+			if len(archive.ProjectFiles) != 1 || len(archive.ProjectFiles[0].InstallationFiles) != 1 {
+				t.Fatalf("got %d project files, want 1 with 1 installation", len(archive.ProjectFiles))
+			}
+			info, err := archive.ProjectFiles[0].Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.ID != "P-0123" || info.Name != "Home" {
+				t.Errorf("got project %+v", info)
+			}
+		})
+	}
+}
+
+func TestETS6Password(t *testing.T) {
+	const want = "U5Z1SRa9+Lezm7oXtBHHEGJQCnhFvrILTwyx/Bmlh/c="
+	if got := ets6Password(testPassword); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,46 @@
+package knxproj
+
+import (
+	"encoding/json"
+
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// Addresses are encoded in JSON as strings, as in "1.1.10" and "1/2/3".
+
+func (d Device) MarshalJSON() ([]byte, error) {
+	type device Device
+	return json.Marshal(struct {
+		Address string
+		device
+	}{d.Address.String(), device(d)})
+}
+
+func (o Object) MarshalJSON() ([]byte, error) {
+	type object Object
+	return json.Marshal(struct {
+		Groups []string
+		object
+	}{groupStrings(o.Groups), object(o)})
+}
+
+func (g Group) MarshalJSON() ([]byte, error) {
+	type group Group
+	var devices []string
+	for _, d := range g.Devices {
+		devices = append(devices, d.String())
+	}
+	return json.Marshal(struct {
+		Address string
+		Devices []string
+		group
+	}{g.Address.String(), devices, group(g)})
+}
+
+func groupStrings(groups []cemi.GroupAddr) []string {
+	var s []string
+	for _, g := range groups {
+		s = append(s, g.String())
+	}
+	return s
+}
@@ -0,0 +1,277 @@
+// Package knxproj reads the parts of an ETS project export (.knxproj) which are
+// useful to knxweb: devices, group addresses with their names, ranges and
+// datapoint types, and the links between them.
+package knxproj
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cespedes/knxweb/ets"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// Project is an installation of an ETS project.
+type Project struct {
+	Name    string
+	Devices []Device // sorted by address
+	Groups  []Group  // sorted by address
+}
+
+// Device is a device of the topology.
+type Device struct {
+	ID       string
+	Address  cemi.IndividualAddr
+	Name     string
+	Area     string   // name of the area it is in
+	Line     string   // name of the line it is in
+	Location []string // building, floor, room... where it is installed, if known
+	Objects  []Object
+}
+
+// Object is a group object of a device linked to some group addresses.
+type Object struct {
+	RefID  string // reference to the group object of the application program
+	Name   string
+	DPT    string // as in "9.001"; empty if not known
+	Flags  string // communication flags, as in "CRWTUI"
+	Groups []cemi.GroupAddr
+}
+
+// Group is a group address.
+type Group struct {
+	Address     cemi.GroupAddr
+	Name        string
+	Ranges      []string // names of the main and middle groups it is in
+	DPT         string   // as in "9.001"; empty if not known
+	MainType    int      // main type of the datapoint type, as in 9; 0 if not known
+	Description string
+	Location    []string              // building, floor, room... of the function it belongs to, if known
+	Devices     []cemi.IndividualAddr // devices with objects linked to it
+}
+
+// Open reads the first installation of the project in an ETS export.
+// The password is only used if the project is protected.
+func Open(filename, password string) (*Project, error) {
+	archive, err := OpenArchive(filename, password)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	// group objects of every application program, by their ID without the program prefix
+	refs := make(map[string]objectInfo)
+	for _, mf := range archive.ManufacturerFiles {
+		md, err := mf.Decode()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", mf.Name, err)
+		}
+		for _, prog := range md.Programs {
+			addProgram(refs, prog)
+		}
+	}
+
+	if len(archive.ProjectFiles) == 0 || len(archive.ProjectFiles[0].InstallationFiles) == 0 {
+		return nil, errors.New("no project found")
+	}
+	instFile := archive.ProjectFiles[0].InstallationFiles[0]
+	data, err := instFile.Decode()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", instFile.Name, err)
+	}
+	if len(data.Installations) == 0 {
+		return nil, errors.New("no installation found")
+	}
+	extra, err := readInstallation(instFile.File)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", instFile.Name, err)
+	}
+	return newProject(data.Installations[0], refs, extra), nil
+}
+
+// objectInfo is what a device instance inherits from the group object definition.
+type objectInfo struct {
+	Name     string
+	DPT      string
+	mainType int
+	flags    [6]bool // C, R, W, T, U, I (read on init)
+}
+
+// Flags returns the communication flags of a group object, as in "CRWTUI".
+func (o objectInfo) Flags() string {
+	var b []byte
+	for i, f := range o.flags {
+		if f {
+			b = append(b, "CRWTUI"[i])
+		}
+	}
+	return string(b)
+}
+
+// addProgram adds the group objects of an application program to refs.
+func addProgram(refs map[string]objectInfo, prog ets.ApplicationProgram) {
+	objects := make(map[ets.ComObjectID]objectInfo)
+	for _, obj := range prog.Objects {
+		name := obj.Text
+		if name == "" {
+			name = obj.Name
+		}
+		if obj.FunctionText != "" {
+			name += " - " + obj.FunctionText
+		}
+		objects[obj.ID] = objectInfo{
+			Name:     name,
+			DPT:      ConvertDPT(obj.DatapointType),
+			mainType: MainType(obj.DatapointType),
+			flags:    [6]bool{obj.CommunicationFlag, obj.ReadFlag, obj.WriteFlag, obj.TransmitFlag, obj.UpdateFlag, obj.ReadOnInitFlag},
+		}
+	}
+	for _, ref := range prog.ObjectRefs {
+		info := objects[ref.RefID]
+		if ref.Text != nil && *ref.Text != "" {
+			info.Name = *ref.Text
+			if ref.FunctionText != nil && *ref.FunctionText != "" {
+				info.Name += " - " + *ref.FunctionText
+			}
+		}
+		if ref.DatapointType != nil && *ref.DatapointType != "" {
+			info.DPT = ConvertDPT(*ref.DatapointType)
+			info.mainType = MainType(*ref.DatapointType)
+		}
+		for i, f := range []*bool{ref.CommunicationFlag, ref.ReadFlag, ref.WriteFlag, ref.TransmitFlag, ref.UpdateFlag, ref.ReadOnInitFlag} {
+			if f != nil {
+				info.flags[i] = *f
+			}
+		}
+		refs[strings.TrimPrefix(string(ref.ID), prog.ID+"_")] = info
+	}
+}
+
+// newProject builds a Project from the data read by package ets and by readInstallation.
+func newProject(inst ets.Installation, refs map[string]objectInfo, extra *installationInfo) *Project {
+	p := &Project{Name: inst.Name}
+
+	groups := make(map[cemi.GroupAddr]*Group)
+	ids := make(map[string]cemi.GroupAddr)
+	// references may be relative to the project, as in "GA-12"
+	resolve := func(ref string) (cemi.GroupAddr, bool) {
+		if addr, ok := ids[ref]; ok {
+			return addr, true
+		}
+		for id, addr := range ids {
+			if strings.HasSuffix(id, "_"+ref) {
+				return addr, true
+			}
+		}
+		return 0, false
+	}
+	for _, ga := range inst.GroupAddresses {
+		g := &Group{Address: ga.Address, Name: ga.Name}
+		if info, ok := extra.Groups[ga.ID]; ok {
+			g.Ranges = info.Ranges
+			g.DPT = ConvertDPT(info.DPT)
+			g.MainType = MainType(info.DPT)
+			g.Description = info.Description
+		}
+		groups[ga.Address] = g
+		ids[ga.ID] = ga.Address
+	}
+	for ref, location := range extra.GroupLocation {
+		if addr, ok := resolve(ref); ok {
+			groups[addr].Location = location
+		}
+	}
+
+	for _, area := range inst.Topology {
+		for _, line := range area.Lines {
+			for _, dev := range line.Devices {
+				d := Device{
+					ID:       dev.ID,
+					Address:  cemi.IndividualAddr(area.Address<<12 | line.Address<<8 | dev.Address),
+					Name:     dev.Name,
+					Area:     area.Name,
+					Line:     line.Name,
+					Location: extra.DeviceLocation[dev.ID],
+				}
+				for _, co := range dev.ComObjects {
+					if len(co.Links) == 0 {
+						continue
+					}
+					info := refs[string(co.RefID)]
+					obj := Object{RefID: string(co.RefID), Name: info.Name, DPT: info.DPT, Flags: info.Flags()}
+					mainType := info.mainType
+					if co.DatapointType != "" {
+						obj.DPT = ConvertDPT(co.DatapointType)
+						mainType = MainType(co.DatapointType)
+					}
+					for _, link := range co.Links {
+						addr, ok := resolve(link)
+						if !ok {
+							continue
+						}
+						obj.Groups = append(obj.Groups, addr)
+						if g, ok := groups[addr]; ok {
+							if g.DPT == "" {
+								g.DPT = obj.DPT
+							}
+							if g.MainType == 0 {
+								g.MainType = mainType
+							}
+							if !hasDevice(g.Devices, d.Address) {
+								g.Devices = append(g.Devices, d.Address)
+							}
+						}
+					}
+					d.Objects = append(d.Objects, obj)
+				}
+				p.Devices = append(p.Devices, d)
+			}
+		}
+	}
+	sort.Slice(p.Devices, func(i, j int) bool { return p.Devices[i].Address < p.Devices[j].Address })
+
+	for _, g := range groups {
+		p.Groups = append(p.Groups, *g)
+	}
+	sort.Slice(p.Groups, func(i, j int) bool { return p.Groups[i].Address < p.Groups[j].Address })
+	return p
+}
+
+func hasDevice(devices []cemi.IndividualAddr, addr cemi.IndividualAddr) bool {
+	for _, d := range devices {
+		if d == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// ConvertDPT converts a datapoint type from ETS ("DPST-9-1") to the format
+// used by knx-go ("9.001").  Only the first of a list of types is used.
+// Main types without subtype ("DPT-9") are not enough to decode values,
+// and are returned as an empty string.
+func ConvertDPT(str string) string {
+	if i := strings.IndexAny(str, " ,"); i >= 0 {
+		str = str[:i]
+	}
+	var main, sub int
+	if n, _ := fmt.Sscanf(str, "DPST-%d-%d", &main, &sub); n == 2 {
+		return fmt.Sprintf("%d.%03d", main, sub)
+	}
+	return ""
+}
+
+// MainType returns the main type of a datapoint type from ETS ("DPT-9" or "DPST-9-1"),
+// or 0 if it is not valid.
+func MainType(str string) int {
+	var main int
+	if _, err := fmt.Sscanf(str, "DPT-%d", &main); err == nil {
+		return main
+	}
+	if _, err := fmt.Sscanf(str, "DPST-%d", &main); err == nil {
+		return main
+	}
+	return 0
+}
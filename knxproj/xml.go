@@ -0,0 +1,96 @@
+package knxproj
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+)
+
+// installationInfo has the data of an installation which package ets does not decode.
+type installationInfo struct {
+	Groups         map[string]groupInfo // by ID
+	DeviceLocation map[string][]string  // by ID of the device instance
+	GroupLocation  map[string][]string  // by ID (or relative ID) of the group address
+}
+
+type groupInfo struct {
+	Ranges      []string
+	DPT         string
+	Description string
+}
+
+// readInstallation reads the group ranges and the locations of an installation file (0.xml).
+// Locations are "BuildingPart"s in ETS5 and "Space"s in ETS6; in the latter, group addresses
+// can be in a room through the functions defined in it.
+func readInstallation(f *zip.File) (*installationInfo, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	info := &installationInfo{
+		Groups:         make(map[string]groupInfo),
+		DeviceLocation: make(map[string][]string),
+		GroupLocation:  make(map[string][]string),
+	}
+	var ranges, location []string
+	inLocations := false
+	d := xml.NewDecoder(rc)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return info, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "GroupRange":
+				ranges = append(ranges, attr(t, "Name"))
+			case "GroupAddress":
+				info.Groups[attr(t, "Id")] = groupInfo{
+					Ranges:      append([]string(nil), ranges...),
+					DPT:         attr(t, "DatapointType"),
+					Description: attr(t, "Description"),
+				}
+			case "Buildings", "Locations":
+				inLocations = true
+			case "BuildingPart", "Space":
+				location = append(location, attr(t, "Name"))
+			case "DeviceInstanceRef":
+				if inLocations {
+					info.DeviceLocation[attr(t, "RefId")] = append([]string(nil), location...)
+				}
+			case "GroupAddressRef":
+				if inLocations {
+					info.GroupLocation[attr(t, "RefId")] = append([]string(nil), location...)
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "GroupRange":
+				if len(ranges) > 0 {
+					ranges = ranges[:len(ranges)-1]
+				}
+			case "Buildings", "Locations":
+				inLocations = false
+			case "BuildingPart", "Space":
+				if len(location) > 0 {
+					location = location[:len(location)-1]
+				}
+			}
+		}
+	}
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/vapourismo/knx-go/knx/cemi"
+	"github.com/vapourismo/knx-go/knx/dpt"
+)
+
+// Diagnostic is a problem found in a config file.
+type Diagnostic struct {
+	Severity string // "error" or "warning"
+	Pos      string `json:",omitempty"` // file and line, if known
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	if d.Pos == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Pos, d.Severity, d.Message)
+}
+
+// lintConfig checks a config which has been read without errors
+// for problems which would only show up at run time.
+func lintConfig(c *Config) []Diagnostic {
+	var diags []Diagnostic
+	add := func(severity, pos, format string, args ...interface{}) {
+		diags = append(diags, Diagnostic{Severity: severity, Pos: pos, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var addrs []cemi.GroupAddr
+	for addr := range c.Addresses {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+
+	// unknown DPTs
+	for _, addr := range addrs {
+		nt := c.Addresses[addr]
+		if _, ok := dpt.Produce(nt.DPT); !ok {
+			add("error", nt.Pos, "unknown DPT %q for %v (%s)", nt.DPT, addr, nt.Name)
+		}
+	}
+
+	// overlapping gateway ranges: /set/ would use the first gateway only
+	for i, gw1 := range c.Gateways {
+		for _, gw2 := range c.Gateways[i+1:] {
+			for _, g1 := range gw1.Groups {
+				for _, g2 := range gw2.Groups {
+					if strings.HasPrefix(g1, g2) || strings.HasPrefix(g2, g1) {
+						add("warning", gw2.Pos, "group range %s of gateway %s overlaps with %s of gateway %s (%s)",
+							g2, gw2.Address, g1, gw1.Address, gw1.Pos)
+					}
+				}
+			}
+		}
+	}
+
+	// names used twice, and names which are prefixes of other names
+	names := make(map[string]cemi.GroupAddr)
+	for _, addr := range addrs {
+		nt := c.Addresses[addr]
+		if old, ok := names[nt.Name]; ok {
+			add("error", nt.Pos, "name %q of %v is also used by %v (%s)", nt.Name, addr, old, c.Addresses[old].Pos)
+			continue
+		}
+		names[nt.Name] = addr
+	}
+	for _, addr := range addrs {
+		nt := c.Addresses[addr]
+		for _, other := range addrs {
+			if strings.HasPrefix(c.Addresses[other].Name, nt.Name+"/") {
+				add("warning", nt.Pos, "name %q of %v is a prefix of %q (%v); /get/%s only returns %v",
+					nt.Name, addr, c.Addresses[other].Name, other, nt.Name, addr)
+				break
+			}
+		}
+	}
+
+	// addresses which cannot be written to
+	if len(c.Gateways) > 0 {
+		for _, addr := range addrs {
+			if !gatewayCovers(c.Gateways, addr) {
+				nt := c.Addresses[addr]
+				add("warning", nt.Pos, "%v (%s) is not in the group range of any gateway", addr, nt.Name)
+			}
+		}
+	}
+	return diags
+}
+
+// gatewayCovers returns true if a group address is in the group range of one of the gateways,
+// as in Server.webSet.
+func gatewayCovers(gateways []Gateway, addr cemi.GroupAddr) bool {
+	str := addr.String()
+	for _, gw := range gateways {
+		for _, g := range gw.Groups {
+			if strings.HasPrefix(str, g) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkConfig reads and checks a config file, and prints the diagnostics
+// as text or JSON.  It returns the exit status: 1 if there are errors, 0 otherwise.
+func checkConfig(filename string, format string) int {
+	var diags []Diagnostic
+	c, err := ReadConfig(filename)
+	if errs, ok := err.(ConfigErrors); ok {
+		for _, e := range errs {
+			diags = append(diags, Diagnostic{Severity: "error", Message: e.Error()})
+		}
+	} else if err != nil {
+		diags = append(diags, Diagnostic{Severity: "error", Message: err.Error()})
+	} else {
+		diags = lintConfig(c)
+	}
+
+	status := 0
+	for _, d := range diags {
+		if d.Severity == "error" {
+			status = 1
+		}
+	}
+	switch format {
+	case "json":
+		if diags == nil {
+			diags = []Diagnostic{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(diags)
+	default:
+		for _, d := range diags {
+			fmt.Println(d)
+		}
+		if len(diags) == 0 {
+			fmt.Printf("%s: OK\n", filename)
+		}
+	}
+	return status
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const LivenessCheckInterval = 30 * time.Second
+
+// LivenessStatus is the current state of an "expect" line in the config file.
+type LivenessStatus struct {
+	Address  string
+	Name     string `json:",omitempty"`
+	Interval string
+	LastSeen time.Time
+	Stale    bool
+}
+
+// lastSeen returns the last time a telegram was received from the device in e,
+// or the last time a value was sent to the group address in e in a GroupWrite or GroupResponse
+// (a GroupRead does not mean that anyone is sending values to it).
+// It must be called with s.Mutex held.
+func (s *Server) lastSeen(e Expectation) (time.Time, bool) {
+	if e.IsGroup {
+		t, ok := s.LastWrite[e.Group]
+		return t, ok
+	}
+	t, ok := s.DevicesSeen[e.Device]
+	return t, ok
+}
+
+// Liveness returns the status of every device or group address with an expected send interval.
+func (s *Server) Liveness() []LivenessStatus {
+	now := time.Now()
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	cfg := getConfig()
+	var result []LivenessStatus
+	for _, e := range cfg.Expects {
+		ls := LivenessStatus{
+			Address:  e.String(),
+			Name:     e.Name(cfg),
+			Interval: e.Interval.String(),
+		}
+		last, ok := s.lastSeen(e)
+		if !ok || last.Before(s.Started) {
+			// not seen yet: give it some time since we started
+			last = s.Started
+		} else {
+			ls.LastSeen = last
+		}
+		ls.Stale = now.Sub(last) > e.Interval
+		result = append(result, ls)
+	}
+	return result
+}
+
+// checkLiveness periodically looks for devices or group addresses
+// which have not sent anything in the expected interval, and sends an alert
+// when they become stale and when they are alive again.
+func (s *Server) checkLiveness() {
+	stale := make(map[string]bool)
+	for {
+		time.Sleep(LivenessCheckInterval)
+		for _, ls := range s.Liveness() {
+			if ls.Stale == stale[ls.Address] {
+				continue
+			}
+			stale[ls.Address] = ls.Stale
+			a := Alert{Time: time.Now(), Address: ls.Address, Name: ls.Name}
+			who := strings.TrimSpace(ls.Address + " " + ls.Name)
+			if ls.Stale {
+				a.Kind = "stale"
+				if ls.LastSeen.IsZero() {
+					a.Message = fmt.Sprintf("%s: not seen since startup (expected every %s)", who, ls.Interval)
+				} else {
+					a.Message = fmt.Sprintf("%s: not seen since %s (expected every %s)", who, ls.LastSeen.Format("2006-01-02 15:04:05"), ls.Interval)
+				}
+			} else {
+				a.Kind = "alive"
+				a.Message = fmt.Sprintf("%s: alive again", who)
+			}
+			s.Alert(a)
+		}
+	}
+}
@@ -1,75 +1,82 @@
 package main
 
 import (
-	"encoding/binary"
 	"fmt"
-	"log"
 	"os"
 	"path"
 	"path/filepath"
 	"time"
-)
 
-// TODO create 2 different logs: knx-log and general log
+	"github.com/cespedes/knxweb/binlog"
+)
 
-var logDir string
-var logFile *os.File
-var logSize int
+// KNX traffic logs.  The application log is in applog.go.
 
 const maxLogSize = 16 * 1024 * 1024
 
-func createLogFile() {
-	var err error
-	t := time.Now()
-	filename := t.Format("20060102-150406.knxlog")
-	if logDir != "" {
-		filename = fmt.Sprintf("%s/%s", logDir, filename)
-	}
-	logFile, err = os.Create(filename)
-	if err != nil {
-		panic(err)
-	}
-	logSize = 0
-}
-
-func LogBinary(k knxMsg) {
-	var buf [1024]byte
-	if logFile == nil {
-		createLogFile()
+// LogBinary writes a telegram to the binary log in config.Logdir,
+// starting a new file when the current one grows too big.
+// s.logMutex must be held.
+func (s *Server) LogBinary(k knxMsg) {
+	if s.binLog == nil {
+		// a new file may be needed in the same second (after a write error),
+		// so existing files get a suffix instead of being truncated
+		base := path.Join(getConfig().Logdir, k.When.Format("20060102-150405"))
+		filename := base + ".knxlog"
+		f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		for i := 1; os.IsExist(err); i++ {
+			filename = fmt.Sprintf("%s-%d.knxlog", base, i)
+			f, err = os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		}
+		if err != nil {
+			appLog.Errorf("binary log: %v", err)
+			return
+		}
+		w, err := binlog.NewWriter(f)
+		if err != nil {
+			appLog.Errorf("binary log: %s: %v", filename, err)
+			f.Close()
+			return
+		}
+		s.binLogFile = f
+		s.binLog = w
+		s.binLogSize = 0
 	}
-	t := uint32(k.When.Unix())
-	binary.BigEndian.PutUint32(buf[:4], t)
-	buf[4] = byte(k.Event.Command)
-	binary.BigEndian.PutUint16(buf[5:7], uint16(k.Event.Source))
-	binary.BigEndian.PutUint16(buf[7:9], uint16(k.Event.Destination))
-	buf[9] = byte(len(k.Event.Data))
-	copy(buf[10:], k.Event.Data)
-	l := 10 + len(k.Event.Data)
-	_, err := logFile.Write(buf[0:l])
+	n, err := s.binLog.Write(binlog.Telegram{Time: k.When, Gateway: k.Where, Event: k.Event})
+	s.binLogSize += n
 	if err != nil {
-		panic(err)
+		appLog.Errorf("binary log: %s: %v", s.binLogFile.Name(), err)
 	}
-	logSize += l
-	if logSize >= maxLogSize {
-		err = logFile.Close()
-		if err != nil {
-			panic(err)
+	if err != nil || s.binLogSize >= maxLogSize {
+		if err := s.binLogFile.Close(); err != nil {
+			appLog.Errorf("binary log: %s: %v", s.binLogFile.Name(), err)
 		}
-		logFile = nil
+		s.binLogFile = nil
+		s.binLog = nil
 	}
 }
 
+// Log writes a telegram to every enabled log.  It is called from the
+// gateway goroutines, so the whole write is done with s.logMutex held.
 func (s *Server) Log(k knxMsg) {
-	// LogBinary(k)
+	s.logMutex.Lock()
+	defer s.logMutex.Unlock()
+	cfg := getConfig()
+	if cfg.Binlog {
+		s.LogBinary(k)
+	}
+	if cfg.JSONLog {
+		s.LogJSON(k)
+	}
 
 	var err error
-	filename := path.Join(config.Logdir, time.Now().Format("2006/0102.log"))
+	filename := path.Join(cfg.Logdir, time.Now().Format("2006/0102.log"))
 	if s.logFileName != filename {
 		s.logFile.Close()
 		os.MkdirAll(filepath.Dir(filename), 0777)
 		s.logFile, err = os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
 		if err != nil {
-			log.Fatal(err)
+			appLog.Fatalf("%v", err)
 		}
 		s.logFileName = filename
 	}
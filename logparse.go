@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// parseLogLine parses a line written by Server.Log (the output of knxMsg.String), as in:
+//
+//	2006-01-02 15:04:05 write: 1.1.10 2/5/7=[12 34] myroom.thermostat myroom/temperature=21.5
+//
+// Only the time, command, source, destination and data are used; the rest is ignored.
+// Text logs do not include the gateway, so Where is left empty.
+func parseLogLine(line string) (knxMsg, error) {
+	var k knxMsg
+	if len(line) < 20 {
+		return k, fmt.Errorf("line too short")
+	}
+	var err error
+	k.When, err = time.ParseInLocation("2006-01-02 15:04:05", line[:19], time.Local)
+	if err != nil {
+		return k, err
+	}
+	fields := strings.SplitN(strings.TrimSpace(line[19:]), " ", 3)
+	if len(fields) < 3 {
+		return k, fmt.Errorf("missing fields")
+	}
+	switch fields[0] {
+	case "read:":
+		k.Event.Command = knx.GroupRead
+	case "response:":
+		k.Event.Command = knx.GroupResponse
+	case "write:":
+		k.Event.Command = knx.GroupWrite
+	default:
+		return k, fmt.Errorf("unknown command %q", fields[0])
+	}
+	k.Event.Source, err = cemi.NewIndividualAddrString(fields[1])
+	if err != nil {
+		return k, err
+	}
+	rest := fields[2]
+	i := strings.Index(rest, "=[")
+	j := strings.IndexByte(rest, ']')
+	if i < 0 || j < i {
+		return k, fmt.Errorf("missing data")
+	}
+	k.Event.Destination, err = cemi.NewGroupAddrString(rest[:i])
+	if err != nil {
+		return k, err
+	}
+	for _, b := range strings.Fields(rest[i+2 : j]) {
+		n, err := strconv.ParseUint(b, 10, 8)
+		if err != nil {
+			return k, err
+		}
+		k.Event.Data = append(k.Event.Data, byte(n))
+	}
+	return k, nil
+}
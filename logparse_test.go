@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+func TestParseLogLine(t *testing.T) {
+	config.Store(&Config{
+		Devices: map[cemi.IndividualAddr]string{0x110A: "myroom.thermostat"},
+		Addresses: map[cemi.GroupAddr]addrNameType{
+			0x1507: {Name: "myroom/temperature", DPT: "9.001"},
+			0x1508: {Name: "myroom/unknown", DPT: "99.999"},
+		},
+	})
+	when := time.Date(2026, 7, 1, 12, 30, 45, 0, time.Local)
+	tests := []struct {
+		name  string
+		event knx.GroupEvent
+	}{
+		{"write", knx.GroupEvent{Command: knx.GroupWrite, Source: 0x110A, Destination: 0x1507, Data: []byte{0, 0x0C, 0x33}}},
+		{"response", knx.GroupEvent{Command: knx.GroupResponse, Source: 0x110A, Destination: 0x1507, Data: []byte{0, 0x07, 0xD0}}},
+		{"read", knx.GroupEvent{Command: knx.GroupRead, Source: 0x1101, Destination: 0x1507}},
+		{"unknown DPT", knx.GroupEvent{Command: knx.GroupWrite, Source: 0x1101, Destination: 0x1508, Data: []byte{1, 2, 3}}},
+		{"not in config", knx.GroupEvent{Command: knx.GroupWrite, Source: 0xFFFF, Destination: 0xFFFF, Data: []byte{255}}},
+	}
+	var logs bytes.Buffer
+	out := appLog.out
+	appLog.out = &logs
+	defer func() { appLog.out = out }()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := knxMsg{When: when, Where: "gw", Event: tt.event}.String()
+			k, err := parseLogLine(line)
+			if err != nil {
+				t.Fatalf("%s: %v", line, err)
+			}
+			if !k.When.Equal(when) || k.Where != "" || k.Event.Command != tt.event.Command ||
+				k.Event.Source != tt.event.Source || k.Event.Destination != tt.event.Destination ||
+				!bytes.Equal(k.Event.Data, tt.event.Data) {
+				t.Errorf("%s: got %+v", line, k)
+			}
+		})
+	}
+	if !strings.Contains(logs.String(), "unknown type 99.999") {
+		t.Errorf("unknown DPT was not logged: %q", logs.String())
+	}
+}
+
+func TestParseLogLineErrors(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"2026-07-01 12:30:45",
+		"2026-07-01 12:30:45 write: 1.1.10",
+		"2026-13-01 12:30:45 write: 1.1.10 2/5/7=[12 34]",
+		"2026-07-01 12:30:45 ???: 1.1.10 2/5/7=[12 34]",
+		"2026-07-01 12:30:45 write: 1.1 2/5/7=[12 34]",
+		"2026-07-01 12:30:45 write: 1.1.10 2/5/7",
+		"2026-07-01 12:30:45 write: 1.1.10 2/5/7=[12 34",
+		"2026-07-01 12:30:45 write: 1.1.10 2-5-7=[12 34]",
+		"2026-07-01 12:30:45 write: 1.1.10 2/5/7=[12 340]",
+		"2026-07-01 12:30:45 write: 1.1.10 2/5/7=[0x12]",
+	} {
+		if k, err := parseLogLine(line); err == nil {
+			t.Errorf("%q: no error, got %+v", line, k)
+		}
+	}
+}
@@ -1,16 +1,16 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
 	"os"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/cespedes/knxweb/binlog"
 	"github.com/vapourismo/knx-go/knx"
 	"github.com/vapourismo/knx-go/knx/cemi"
 	"github.com/vapourismo/knx-go/knx/dpt"
@@ -23,7 +23,14 @@ const (
 	MessagesSizeTrunc = 248 * 1024      // When maximum reached, shrink to this
 )
 
-var config *Config
+// config holds the current *Config.  It is replaced when the config file is
+// reloaded, so it must be read with getConfig, once for every telegram or request.
+var config atomic.Value
+
+func getConfig() *Config {
+	c, _ := config.Load().(*Config)
+	return c
+}
 
 type Server struct {
 	Debug bool
@@ -35,8 +42,32 @@ type Server struct {
 
 	Conns map[string]knx.GroupTunnel
 
+	Stats     Stats
+	Inventory Inventory
+
+	Started     time.Time
+	DevicesSeen map[cemi.IndividualAddr]time.Time
+	LastWrite   map[cemi.GroupAddr]time.Time                         // last GroupWrite or GroupResponse to every group address
+	SentTo      map[cemi.IndividualAddr]map[cemi.GroupAddr]time.Time // last telegram from every device to every group address
+	SeenOn      map[cemi.IndividualAddr]map[string]time.Time         // last telegram from every device on every gateway
+	WroteTo     map[cemi.IndividualAddr]map[cemi.GroupAddr]time.Time // last GroupWrite or GroupResponse from every device to every group address
+	AlertSinks  []AlertSink
+	Rules       Rules
+	Replay      Replay
+
+	logMutex    sync.Mutex // held while writing a telegram to the logs
 	logFile     *os.File
 	logFileName string
+	binLogFile  *os.File
+	binLog      *binlog.Writer
+	binLogSize  int
+
+	jsonLogFile  *os.File
+	jsonLogDay   string
+	jsonLogIndex int
+	jsonLogSize  int64
+
+	snapshotMutex sync.Mutex
 }
 
 type knxMsg struct {
@@ -46,6 +77,7 @@ type knxMsg struct {
 }
 
 func (k knxMsg) String() string {
+	cfg := getConfig()
 	str := k.When.Format("2006-01-02 15:04:05")
 	switch k.Event.Command {
 	case knx.GroupRead:
@@ -58,17 +90,17 @@ func (k knxMsg) String() string {
 		str += " ???:"
 	}
 	str += " " + k.Event.Source.String() + " " + k.Event.Destination.String() + "=" + fmt.Sprint(k.Event.Data)
-	if dev, ok := config.Devices[k.Event.Source]; ok {
+	if dev, ok := cfg.Devices[k.Event.Source]; ok {
 		str += " " + dev
 	}
-	if nt, ok := config.Addresses[k.Event.Destination]; ok {
+	if nt, ok := cfg.Addresses[k.Event.Destination]; ok {
 		dp, ok := dpt.Produce(nt.DPT)
 		if !ok {
-			fmt.Printf("Warning: unknown type %v in config file\n", nt.DPT)
+			appLog.Warnf("unknown type %v in config file", nt.DPT)
 			dp = new(UnknownDPT)
 		}
 		if err := dp.Unpack(k.Event.Data); err != nil {
-			fmt.Printf("Network: Error parsing %v for %v\n", k.Event.Data, k.Event.Destination)
+			appLog.Warnf("Error parsing %v for %v", k.Event.Data, k.Event.Destination)
 		} else {
 			str += " " + nt.Name + "=" + fmt.Sprint(dp)
 		}
@@ -78,53 +110,70 @@ func (k knxMsg) String() string {
 
 func (s *Server) knxNewMessage(gateway string, event knx.GroupEvent) {
 	msg := knxMsg{When: time.Now(), Where: gateway, Event: event}
-	s.Log(msg)
+	if gateway != ReplayGateway {
+		// recorded traffic must not end up in the logs again
+		s.Log(msg)
+	}
 	s.Mutex.Lock()
 	s.Messages = append(s.Messages, msg)
-	if l := len(s.Messages); l%10 == 0 {
-		log.Printf("Messages size: %d", l)
-	}
 	if l := len(s.Messages); l > MessagesSizeMax {
 		s.Messages = s.Messages[l-MessagesSizeTrunc:]
-		log.Printf("Messages grew to %d entries; shrinking to %d", l, MessagesSizeTrunc)
+		appLog.Infof("Messages grew to %d entries; shrinking to %d", l, MessagesSizeTrunc)
 	}
-	log.Printf("New destination group addr: %v", event.Destination)
 	if _, ok := s.Values[event.Destination]; !ok {
 		// this destination has not been seen yet
-		if s.Debug {
-			log.Printf("New destination group addr: %v", event.Destination)
-		}
+		appLog.Debugf("New destination group addr: %v", event.Destination)
 		s.SortedValues = append(s.SortedValues, event.Destination)
 		sort.Slice(s.SortedValues, func(i, j int) bool { return s.SortedValues[i] < s.SortedValues[j] })
 	}
 	s.Values[event.Destination] = msg
+	s.DevicesSeen[event.Source] = msg.When
+	if event.Command != knx.GroupRead {
+		s.LastWrite[event.Destination] = msg.When
+	}
+	if s.SentTo[event.Source] == nil {
+		s.SentTo[event.Source] = make(map[cemi.GroupAddr]time.Time)
+	}
+	s.SentTo[event.Source][event.Destination] = msg.When
+	if event.Source != 0 {
+		// telegrams sent with /set/ have no source
+		if s.SeenOn[event.Source] == nil {
+			s.SeenOn[event.Source] = make(map[string]time.Time)
+		}
+		s.SeenOn[event.Source][gateway] = msg.When
+	}
+	if event.Command != knx.GroupRead {
+		if s.WroteTo[event.Source] == nil {
+			s.WroteTo[event.Source] = make(map[cemi.GroupAddr]time.Time)
+		}
+		s.WroteTo[event.Source][event.Destination] = msg.When
+	}
 	s.Mutex.Unlock()
-	fmt.Println(msg)
-	// log.Printf("KNX: %+v", event)
-	// b, _ := json.Marshal(event)
-	// log.Printf("JSON: %v", string(b))
+	s.Stats.Add(msg)
+	s.Inventory.Add(msg)
+	s.updateRules(msg)
+	appLog.Debugf("%s", msg)
 }
 
 func (s *Server) knxGetMessages() {
-	for i, gw := range config.Gateways {
+	cfg := getConfig()
+	for i, gw := range cfg.Gateways {
 		if !strings.Contains(gw.Address, ":") {
-			config.Gateways[i].Address = fmt.Sprintf("%s:%d", gw.Address, KNXDefaultPort)
+			cfg.Gateways[i].Address = fmt.Sprintf("%s:%d", gw.Address, KNXDefaultPort)
 		}
 	}
-	if s.Debug {
-		fmt.Printf("gateways: %v\n", config.Gateways)
-	}
+	appLog.Debugf("gateways: %v", cfg.Gateways)
 
 	s.Conns = make(map[string]knx.GroupTunnel)
-	for _, gw := range config.Gateways {
+	for _, gw := range cfg.Gateways {
 		go func(gwName string) {
 			for {
-				log.Printf("Stablishing connection to KNX gateway %s...\n", gwName)
+				appLog.Infof("Establishing connection to KNX gateway %s...", gwName)
 
 				client, err := knx.NewGroupTunnel(gwName, knx.DefaultTunnelConfig)
 				if err != nil {
-					log.Printf("knx.NewGroupTunnel (%s): %s", gwName, err.Error())
-					log.Printf("Sleeping %s...", KNXTimeout/4)
+					appLog.Errorf("knx.NewGroupTunnel (%s): %s", gwName, err.Error())
+					appLog.Infof("Sleeping %s...", KNXTimeout/4)
 					time.Sleep(KNXTimeout / 4)
 					continue
 				}
@@ -139,11 +188,11 @@ func (s *Server) knxGetMessages() {
 				for {
 					select {
 					case <-time.After(KNXTimeout):
-						log.Printf("timeout (%s)", KNXTimeout)
+						appLog.Warnf("timeout (%s) on gateway %s", KNXTimeout, gwName)
 						break innerLoop
 					case event, ok := <-knxChan:
 						if !ok {
-							log.Printf("Error reading from KNX channel")
+							appLog.Errorf("Error reading from KNX channel of gateway %s", gwName)
 							break innerLoop
 						}
 						s.knxNewMessage(gwName, event)
@@ -160,79 +209,91 @@ func (s *Server) knxGetMessages() {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "dump":
+			os.Exit(dumpCommand(os.Args[2:]))
+		case "import-ets":
+			os.Exit(importETSCommand(os.Args[2:]))
+		case "ets-diff":
+			os.Exit(etsDiffCommand(os.Args[2:]))
+		case "export-ets":
+			os.Exit(exportETSCommand(os.Args[2:]))
+		}
+	}
+
 	var s Server
+	s.Started = time.Now()
 	s.Values = make(map[cemi.GroupAddr]knxMsg)
+	s.DevicesSeen = make(map[cemi.IndividualAddr]time.Time)
+	s.LastWrite = make(map[cemi.GroupAddr]time.Time)
+	s.SentTo = make(map[cemi.IndividualAddr]map[cemi.GroupAddr]time.Time)
+	s.SeenOn = make(map[cemi.IndividualAddr]map[string]time.Time)
+	s.WroteTo = make(map[cemi.IndividualAddr]map[cemi.GroupAddr]time.Time)
 	debug := flag.Bool("debug", false, "debugging info")
 	configFile := flag.String("config", "knx.cfg", "config file")
 	logdir := flag.String("logdir", "", "directory where logs are stored")
+	replay := flag.String("replay", "", "replay telegrams from this text or binary log")
+	replaySpeed := flag.Float64("replay-speed", 1, "replay speed (1: real time, 0: as fast as possible)")
+	replayStep := flag.Bool("replay-step", false, "replay one telegram at a time (use /api/replay/step)")
+	check := flag.Bool("check", false, "check the config file and exit")
+	checkFormat := flag.String("check-format", "text", "output of -check: \"text\" or \"json\"")
 	flag.Parse()
 	if *debug {
 		s.Debug = true
 	}
-	if *logdir != "" {
-		logDir = *logdir
-		fmt.Printf("logdir = %s\n", logDir)
+	if *check {
+		os.Exit(checkConfig(*configFile, *checkFormat))
 	}
 
-	var err error
-	config, err = ReadConfig(*configFile)
+	cfg, err := ReadConfig(*configFile)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
-	if len(config.Gateways) == 0 {
-		log.Fatal("No KNX gateway specified.  Please use \"gateway xx.xx.xx.xx\" in config file.")
+	if err := appLog.Setup(cfg.LogLevel, cfg.LogFormat, cfg.LogOutput); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 	if *debug {
-		fmt.Printf("gateways: %v\n", config.Gateways)
-		fmt.Printf("devices: %v\n", config.Devices)
-		fmt.Printf("addresses: %v\n", config.Addresses)
+		appLog.SetLevel(LevelDebug)
 	}
-
-	func() {
-		// TODO: specify file location in config file
-		// TODO: compress?
-		file, err := os.Open("status.json")
-		if err != nil {
-			log.Println(err)
-			return
-		}
-		defer file.Close()
-		decoder := json.NewDecoder(file)
-		err = decoder.Decode(&s.Values)
+	if *logdir != "" {
+		cfg.Logdir = *logdir
+		appLog.Infof("logdir = %s", cfg.Logdir)
+	}
+	if len(cfg.Gateways) == 0 && *replay == "" {
+		appLog.Fatalf("No KNX gateway specified.  Please use \"gateway xx.xx.xx.xx\" in config file.")
+	}
+	appLog.Debugf("gateways: %v", cfg.Gateways)
+	appLog.Debugf("devices: %v", cfg.Devices)
+	appLog.Debugf("addresses: %v", cfg.Addresses)
+	config.Store(cfg)
+	for _, ac := range cfg.Alerts {
+		sink, err := NewAlertSink(ac)
 		if err != nil {
-			log.Println(err)
-			return
-		}
-		for key := range s.Values {
-			s.SortedValues = append(s.SortedValues, key)
+			appLog.Fatalf("%v", err)
 		}
-		sort.Slice(s.SortedValues, func(i, j int) bool { return s.SortedValues[i] < s.SortedValues[j] })
+		s.AlertSinks = append(s.AlertSinks, sink)
+	}
+	s.Rules.Init(cfg.Rules)
 
-	}()
+	s.loadSnapshot()
+	if cfg.History > 0 {
+		s.loadHistory(cfg.History)
+	}
 
 	go s.knxGetMessages()
-	go func() {
-		for {
-			time.Sleep(30 * time.Second)
-			if s.Debug {
-				log.Println("Writing status to disk")
-			}
-			// TODO: create file atomically (race!)
-			// TODO: specify file location in config file
-			// TODO: compress?
-			file, err := os.Create("status.json")
-			if err != nil {
-				log.Println(err)
-				return
-			}
-			encoder := json.NewEncoder(file)
-			s.Mutex.Lock()
-			err = encoder.Encode(s.Values)
-			s.Mutex.Unlock()
-			file.Close()
+	if *replay != "" {
+		if err := s.StartReplay(*replay, *replaySpeed, *replayStep); err != nil {
+			appLog.Fatalf("%v", err)
 		}
-	}()
+	}
+	go s.checkLiveness()
+	go s.checkRules()
+	go s.snapshotLoop()
+	go s.snapshotOnShutdown()
+	go s.reloadOnHangup(*configFile)
 
 	s.WebServer()
 }
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cespedes/knxweb/binlog"
+)
+
+// ReplayGateway is the name of the pseudo gateway used for replayed telegrams.
+// Nothing is ever sent to it.
+const ReplayGateway = "replay"
+
+// ReplayStatus is the state of a replay.
+type ReplayStatus struct {
+	Running bool
+	File    string
+	Speed   float64 // 1: real time; 2: twice as fast...; 0: as fast as possible
+	Step    bool    // wait for a call to StepReplay before each telegram
+	Sent    int
+	Total   int
+}
+
+// Replay feeds telegrams recorded in a log back into the server.
+type Replay struct {
+	mutex  sync.Mutex
+	status ReplayStatus
+	stop   chan struct{}
+	step   chan struct{}
+}
+
+// readLogFile reads all the telegrams in a binary log or in a daily text log.
+func readLogFile(filename string) ([]knxMsg, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	var msgs []knxMsg
+	if magic, _ := br.Peek(len(binlog.Magic)); bytes.Equal(magic, []byte(binlog.Magic)) {
+		r, err := binlog.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			t, err := r.Next()
+			if err == io.EOF {
+				return msgs, nil
+			}
+			if err != nil {
+				return msgs, err
+			}
+			msgs = append(msgs, knxMsg{When: t.Time, Where: t.Gateway, Event: t.Event})
+		}
+	}
+	s := bufio.NewScanner(br)
+	lineNum := 0
+	for s.Scan() {
+		lineNum++
+		k, err := parseLogLine(s.Text())
+		if err != nil {
+			return msgs, fmt.Errorf("%s line %d: %w", filename, lineNum, err)
+		}
+		msgs = append(msgs, k)
+	}
+	return msgs, s.Err()
+}
+
+// StartReplay starts replaying the telegrams in a log file.
+func (s *Server) StartReplay(filename string, speed float64, step bool) error {
+	msgs, err := readLogFile(filename)
+	if err != nil {
+		return err
+	}
+	r := &s.Replay
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.status.Running {
+		return fmt.Errorf("replay already running")
+	}
+	r.status = ReplayStatus{
+		Running: true,
+		File:    filename,
+		Speed:   speed,
+		Step:    step,
+		Total:   len(msgs),
+	}
+	r.stop = make(chan struct{})
+	r.step = make(chan struct{})
+	go s.replay(msgs, r.stop, r.step)
+	return nil
+}
+
+func (s *Server) replay(msgs []knxMsg, stop, step chan struct{}) {
+	r := &s.Replay
+	defer func() {
+		r.mutex.Lock()
+		if r.stop == stop {
+			r.status.Running = false
+		}
+		r.mutex.Unlock()
+	}()
+	for i, k := range msgs {
+		r.mutex.Lock()
+		speed, stepping := r.status.Speed, r.status.Step
+		r.mutex.Unlock()
+		if stepping {
+			select {
+			case <-stop:
+				return
+			case <-step:
+			}
+		} else if i > 0 && speed > 0 {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Duration(float64(k.When.Sub(msgs[i-1].When)) / speed)):
+			}
+		}
+		s.knxNewMessage(ReplayGateway, k.Event)
+		r.mutex.Lock()
+		r.status.Sent++
+		r.mutex.Unlock()
+	}
+}
+
+// StepReplay lets a stepwise replay send its next telegram.
+func (s *Server) StepReplay() error {
+	r := &s.Replay
+	r.mutex.Lock()
+	running, step := r.status.Running, r.step
+	r.mutex.Unlock()
+	if !running {
+		return fmt.Errorf("replay not running")
+	}
+	select {
+	case step <- struct{}{}:
+		return nil
+	case <-time.After(time.Second):
+		return fmt.Errorf("replay not waiting for a step")
+	}
+}
+
+// StopReplay stops the current replay.
+func (s *Server) StopReplay() error {
+	r := &s.Replay
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.status.Running {
+		return fmt.Errorf("replay not running")
+	}
+	close(r.stop)
+	r.status.Running = false
+	return nil
+}
+
+// ReplayState returns the state of the replay.
+func (s *Server) ReplayState() ReplayStatus {
+	r := &s.Replay
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.status
+}
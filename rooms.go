@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// roomRef is a device or group address put in a room with a "room" directive.
+type roomRef struct {
+	Room   string
+	Target string // individual or group address, or name of a group address
+	Pos    string
+}
+
+// setRoom puts a device or group address in a room.
+func (c *Config) setRoom(ref roomRef) error {
+	if !strings.Contains(ref.Target, "/") {
+		if addr, err := cemi.NewIndividualAddrString(ref.Target); err == nil {
+			if _, ok := c.Devices[addr]; !ok {
+				return fmt.Errorf("unknown device %s", ref.Target)
+			}
+			c.DeviceRooms[addr] = ref.Room
+			return nil
+		}
+	}
+	addr, err := c.groupAddr(ref.Target)
+	if err != nil {
+		return err
+	}
+	nt, ok := c.Addresses[addr]
+	if !ok {
+		return fmt.Errorf("unknown group address %s", ref.Target)
+	}
+	nt.Room = ref.Room
+	c.Addresses[addr] = nt
+	return nil
+}
+
+// RoomEntry is a device or group address in a room.
+type RoomEntry struct {
+	Address string
+	Name    string
+}
+
+// Room is a location with the devices and group addresses in it.
+// Rooms are named by their path, as in "house/first/kitchen".
+type Room struct {
+	Name    string
+	Devices []RoomEntry
+	Groups  []RoomEntry
+}
+
+// Rooms returns every room with something in it, and the rooms containing them.
+func Rooms() []Room {
+	cfg := getConfig()
+	rooms := make(map[string]*Room)
+	get := func(name string) *Room {
+		// parents are listed too, even if empty
+		for i, c := range name {
+			if _, ok := rooms[name[:i]]; c == '/' && !ok {
+				rooms[name[:i]] = &Room{Name: name[:i]}
+			}
+		}
+		r, ok := rooms[name]
+		if !ok {
+			r = &Room{Name: name}
+			rooms[name] = r
+		}
+		return r
+	}
+	for addr, room := range cfg.DeviceRooms {
+		if room == "" {
+			continue
+		}
+		r := get(room)
+		r.Devices = append(r.Devices, RoomEntry{Address: addr.String(), Name: cfg.Devices[addr]})
+	}
+	for addr, nt := range cfg.Addresses {
+		if nt.Room == "" || hasFlag(nt.Flags, "hidden") {
+			continue
+		}
+		r := get(nt.Room)
+		r.Groups = append(r.Groups, RoomEntry{Address: addr.String(), Name: nt.Name})
+	}
+
+	var result []Room
+	for _, r := range rooms {
+		sort.Slice(r.Devices, func(i, j int) bool { return r.Devices[i].Address < r.Devices[j].Address })
+		sort.Slice(r.Groups, func(i, j int) bool { return r.Groups[i].Address < r.Groups[j].Address })
+		result = append(result, *r)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// RoomValues is the current state of the group addresses in a room and the rooms inside it.
+type RoomValues struct {
+	Room    string
+	Values  []jsonLogRecord
+	NotSeen []RoomEntry // group addresses with no telegrams yet
+}
+
+// RoomValues returns the last values of the group addresses in a room, or false if there is no such room.
+// Rooms with devices but no group addresses have no values.
+func (s *Server) RoomValues(room string) (RoomValues, bool) {
+	cfg := getConfig()
+	rv := RoomValues{Room: room, Values: []jsonLogRecord{}}
+	in := func(r string) bool {
+		return r == room || strings.HasPrefix(r, room+"/")
+	}
+	found := false
+	for _, r := range cfg.DeviceRooms {
+		if in(r) {
+			found = true
+			break
+		}
+	}
+	var addrs []cemi.GroupAddr
+	for addr, nt := range cfg.Addresses {
+		if hasFlag(nt.Flags, "hidden") {
+			continue
+		}
+		if in(nt.Room) {
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 && !found {
+		return rv, false
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	for _, addr := range addrs {
+		if k, ok := s.Values[addr]; ok {
+			rv.Values = append(rv.Values, newJSONLogRecord(k))
+		} else {
+			rv.NotSeen = append(rv.NotSeen, RoomEntry{Address: addr.String(), Name: cfg.Addresses[addr].Name})
+		}
+	}
+	return rv, true
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+const SearchDefaultLimit = 1000
+
+// parseJSONLogLine parses a line written by Server.LogJSON.
+func parseJSONLogLine(line []byte) (knxMsg, error) {
+	var rec jsonLogRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return knxMsg{}, err
+	}
+	return rec.knxMsg()
+}
+
+// dayLogFiles returns the logs of a day in config.Logdir, in order:
+// the JSON logs if there are any, or the text log otherwise.
+func dayLogFiles(day time.Time) []string {
+	cfg := getConfig()
+	var files []string
+	for i := 0; ; i++ {
+		filename := jsonLogName(day, i)
+		if _, err := os.Stat(filename); err == nil {
+			files = append(files, filename)
+		} else if _, err := os.Stat(filename + ".gz"); err == nil {
+			files = append(files, filename+".gz")
+		} else {
+			break
+		}
+	}
+	if len(files) == 0 {
+		filename := path.Join(cfg.Logdir, day.Format("2006/0102.log"))
+		if _, err := os.Stat(filename); err == nil {
+			files = append(files, filename)
+		}
+	}
+	return files
+}
+
+// searchLogFile calls fn for every telegram in a log file, until fn returns false.
+func searchLogFile(filename string, fn func(knxMsg) bool) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if strings.HasSuffix(filename, ".gz") {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	isJSON := strings.HasSuffix(filename, ".jsonl") || strings.HasSuffix(filename, ".jsonl.gz")
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		var k knxMsg
+		var err error
+		if isJSON {
+			k, err = parseJSONLogLine(sc.Bytes())
+		} else {
+			k, err = parseLogLine(sc.Text())
+		}
+		if err != nil {
+			continue
+		}
+		if !fn(k) {
+			return nil
+		}
+	}
+	return sc.Err()
+}
+
+// parseSearchTime accepts dates, dates with times, and RFC 3339 timestamps.
+func parseSearchTime(str string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, str, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Parse(time.RFC3339, str)
+}
+
+// parseValuePredicate parses a condition on values such as ">20", "<=5" or "=true".
+func parseValuePredicate(str string) (Rule, error) {
+	var r Rule
+	for _, op := range []string{">=", "<=", "!=", ">", "<", "="} {
+		if strings.HasPrefix(str, op) {
+			r.Op = op
+			break
+		}
+	}
+	if r.Op == "" {
+		r.Op = "="
+	}
+	var err error
+	r.Value, err = parseRuleValue(strings.TrimPrefix(str, r.Op))
+	return r, err
+}
+
+// apiSearch searches the logs in config.Logdir, and streams the matching telegrams in JSON Lines.
+//
+// Parameters: from, to, addr, source, command, value (as in ">20") and limit.
+func (s *Server) apiSearch(w http.ResponseWriter, r *http.Request) {
+	var f telegramFilter
+	var err error
+	badRequest := func(err error) {
+		http.Error(w, fmt.Sprintf("400 Bad Request: %s", err.Error()), http.StatusBadRequest)
+	}
+
+	f.To = time.Now()
+	if str := r.FormValue("to"); str != "" {
+		if f.To, err = parseSearchTime(str); err != nil {
+			badRequest(err)
+			return
+		}
+	}
+	f.From = f.To.Add(-24 * time.Hour)
+	if str := r.FormValue("from"); str != "" {
+		if f.From, err = parseSearchTime(str); err != nil {
+			badRequest(err)
+			return
+		}
+	}
+	if str := r.FormValue("addr"); str != "" {
+		if f.Addrs = s.getAddrs(str); len(f.Addrs) == 0 {
+			http.Error(w, "404 Not Found", http.StatusNotFound)
+			return
+		}
+	}
+	if str := r.FormValue("source"); str != "" {
+		a, err := cemi.NewIndividualAddrString(str)
+		if err != nil {
+			badRequest(err)
+			return
+		}
+		f.Sources = []cemi.IndividualAddr{a}
+	}
+	f.Command = r.FormValue("command")
+	var pred *Rule
+	if str := r.FormValue("value"); str != "" {
+		p, err := parseValuePredicate(str)
+		if err != nil {
+			badRequest(err)
+			return
+		}
+		pred = &p
+	}
+	limit := SearchDefaultLimit
+	if str := r.FormValue("limit"); str != "" {
+		if limit, err = strconv.Atoi(str); err != nil {
+			badRequest(err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	found := 0
+	match := func(k knxMsg) bool {
+		select {
+		case <-r.Context().Done():
+			return false
+		default:
+		}
+		if !f.Match(k) {
+			return true
+		}
+		if pred != nil {
+			v, err := decodeValue(k)
+			if err != nil || !pred.Match(v, false) {
+				return true
+			}
+		}
+		if err := enc.Encode(newJSONLogRecord(k)); err != nil {
+			return false
+		}
+		found++
+		if flusher != nil && found%100 == 0 {
+			flusher.Flush()
+		}
+		return limit <= 0 || found < limit
+	}
+	for day := f.From; day.Before(f.To.AddDate(0, 0, 1)); day = day.AddDate(0, 0, 1) {
+		for _, filename := range dayLogFiles(day) {
+			if err := searchLogFile(filename, match); err != nil {
+				appLog.Warnf("search: %s: %v", filename, err)
+			}
+			if (limit > 0 && found >= limit) || r.Context().Err() != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+func TestParseJSONLogLine(t *testing.T) {
+	config.Store(&Config{
+		Devices: map[cemi.IndividualAddr]string{0x110A: "myroom.thermostat"},
+		Addresses: map[cemi.GroupAddr]addrNameType{
+			0x1507: {Name: "myroom/temperature", DPT: "9.001"},
+			0x1508: {Name: "myroom/unknown", DPT: "99.999"},
+		},
+	})
+	when := time.Date(2026, 7, 1, 12, 30, 45, 123456789, time.Local)
+	tests := []struct {
+		name  string
+		event knx.GroupEvent
+	}{
+		{"write", knx.GroupEvent{Command: knx.GroupWrite, Source: 0x110A, Destination: 0x1507, Data: []byte{0, 0x0C, 0x33}}},
+		{"response", knx.GroupEvent{Command: knx.GroupResponse, Source: 0x110A, Destination: 0x1507, Data: []byte{0, 0x07, 0xD0}}},
+		{"read", knx.GroupEvent{Command: knx.GroupRead, Source: 0x1101, Destination: 0x1507}},
+		{"unknown DPT", knx.GroupEvent{Command: knx.GroupWrite, Source: 0x1101, Destination: 0x1508, Data: []byte{1, 2, 3}}},
+		{"not in config", knx.GroupEvent{Command: knx.GroupWrite, Source: 0xFFFF, Destination: 0xFFFF, Data: []byte{255}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := json.Marshal(newJSONLogRecord(knxMsg{When: when, Where: "gw", Event: tt.event}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			k, err := parseJSONLogLine(line)
+			if err != nil {
+				t.Fatalf("%s: %v", line, err)
+			}
+			if !k.When.Equal(when) || k.Where != "gw" || k.Event.Command != tt.event.Command ||
+				k.Event.Source != tt.event.Source || k.Event.Destination != tt.event.Destination ||
+				!bytes.Equal(k.Event.Data, tt.event.Data) {
+				t.Errorf("%s: got %+v", line, k)
+			}
+		})
+	}
+}
+
+func TestParseJSONLogLineErrors(t *testing.T) {
+	for _, line := range []string{
+		``,
+		`{"Time":"2026-07-01T12:30:45Z","Command":"write"`,
+		`["write"]`,
+		`{"Time":"yesterday","Gateway":"gw","Command":"write","Source":"1.1.10","Destination":"2/5/7","Data":"0c"}`,
+		`{"Time":"2026-07-01T12:30:45Z","Gateway":"gw","Command":"delete","Source":"1.1.10","Destination":"2/5/7","Data":"0c"}`,
+		`{"Time":"2026-07-01T12:30:45Z","Gateway":"gw","Command":"write","Source":"1/1/10","Destination":"2/5/7","Data":"0c"}`,
+		`{"Time":"2026-07-01T12:30:45Z","Gateway":"gw","Command":"write","Source":"1.1.10","Destination":"2.5.7","Data":"0c"}`,
+		`{"Time":"2026-07-01T12:30:45Z","Gateway":"gw","Command":"write","Source":"1.1.10","Destination":"2/5/7","Data":"0xc"}`,
+		`{"Time":"2026-07-01T12:30:45Z","Gateway":"gw","Command":"write","Source":"1.1.10","Destination":"2/5/7","Data":12}`,
+	} {
+		if k, err := parseJSONLogLine([]byte(line)); err == nil {
+			t.Errorf("%s: no error, got %+v", line, k)
+		}
+	}
+}
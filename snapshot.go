@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+const (
+	DefaultSnapshotFile     = "status.json"
+	DefaultSnapshotInterval = 30 * time.Second
+)
+
+// SnapshotVersion is the version of the snapshot format written by saveSnapshot.
+// Version 1 was a plain JSON encoding of Server.Values, without any version field.
+const SnapshotVersion = 2
+
+// Snapshot is the content of a status snapshot file.
+type Snapshot struct {
+	Version  int
+	Saved    time.Time
+	Gateways []SnapshotGateway
+	Values   []jsonLogRecord // last telegram sent to every group address
+}
+
+// SnapshotGateway is the state of a gateway when the snapshot was saved.
+type SnapshotGateway struct {
+	Address   string
+	Connected bool
+}
+
+// loadSnapshot reads the last values of every group address from config.Snapshot.
+// The file may be compressed or not, and in the current or the legacy format.
+// Entries which are not valid are dropped and reported.
+func (s *Server) loadSnapshot() {
+	cfg := getConfig()
+	file, err := os.Open(cfg.Snapshot)
+	if err != nil {
+		appLog.Warnf("%v", err)
+		return
+	}
+	defer file.Close()
+	br := bufio.NewReader(file)
+	var r io.Reader = br
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			appLog.Errorf("%s: %v", cfg.Snapshot, err)
+			return
+		}
+		defer zr.Close()
+		r = zr
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		appLog.Errorf("%s: %v", cfg.Snapshot, err)
+		return
+	}
+	values, err := parseSnapshot(b)
+	if err != nil {
+		appLog.Errorf("%s: %v", cfg.Snapshot, err)
+		return
+	}
+
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	s.Values = values
+	s.SortedValues = s.SortedValues[:0]
+	for key := range s.Values {
+		s.SortedValues = append(s.SortedValues, key)
+	}
+	sort.Slice(s.SortedValues, func(i, j int) bool { return s.SortedValues[i] < s.SortedValues[j] })
+}
+
+// parseSnapshot decodes a snapshot, migrating it from the legacy format if needed.
+func parseSnapshot(b []byte) (map[cemi.GroupAddr]knxMsg, error) {
+	cfg := getConfig()
+	var header struct {
+		Version int
+	}
+	// The legacy format is an object keyed by group address, without "Version"
+	if err := json.Unmarshal(b, &header); err != nil {
+		return nil, err
+	}
+	values := make(map[cemi.GroupAddr]knxMsg)
+	dropped := 0
+	drop := func(what string, err error) {
+		appLog.Warnf("%s: dropping %s: %v", cfg.Snapshot, what, err)
+		dropped++
+	}
+	switch header.Version {
+	case 0:
+		var legacy map[cemi.GroupAddr]knxMsg
+		if err := json.Unmarshal(b, &legacy); err != nil {
+			return nil, err
+		}
+		for addr, k := range legacy {
+			if err := validateSnapshotValue(k); err != nil {
+				drop(addr.String(), err)
+				continue
+			}
+			if k.Event.Destination != addr {
+				drop(addr.String(), fmt.Errorf("destination is %v", k.Event.Destination))
+				continue
+			}
+			values[addr] = k
+		}
+		appLog.Infof("%s: migrated %d values from the legacy format", cfg.Snapshot, len(values))
+	case SnapshotVersion:
+		var snap Snapshot
+		if err := json.Unmarshal(b, &snap); err != nil {
+			return nil, err
+		}
+		for i, rec := range snap.Values {
+			k, err := rec.knxMsg()
+			if err == nil {
+				err = validateSnapshotValue(k)
+			}
+			if err != nil {
+				drop(fmt.Sprintf("value %d (%s)", i, rec.Destination), err)
+				continue
+			}
+			if old, ok := values[k.Event.Destination]; ok && old.When.After(k.When) {
+				drop(fmt.Sprintf("value %d (%s)", i, rec.Destination), fmt.Errorf("duplicate address"))
+				continue
+			}
+			values[k.Event.Destination] = k
+		}
+	default:
+		return nil, fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+	if dropped > 0 {
+		appLog.Warnf("%s: %d values loaded, %d dropped", cfg.Snapshot, len(values), dropped)
+	}
+	return values, nil
+}
+
+func validateSnapshotValue(k knxMsg) error {
+	if k.When.IsZero() {
+		return fmt.Errorf("no timestamp")
+	}
+	if k.When.After(time.Now().Add(time.Hour)) {
+		return fmt.Errorf("timestamp %s is in the future", k.When.Format("2006-01-02 15:04:05"))
+	}
+	if commandName(k.Event.Command) == "???" {
+		return fmt.Errorf("invalid command %d", k.Event.Command)
+	}
+	return nil
+}
+
+// newSnapshot returns the current state of the server.
+func (s *Server) newSnapshot() Snapshot {
+	snap := Snapshot{Version: SnapshotVersion, Saved: time.Now()}
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	for _, gw := range getConfig().Gateways {
+		_, ok := s.Conns[gw.Address]
+		snap.Gateways = append(snap.Gateways, SnapshotGateway{Address: gw.Address, Connected: ok})
+	}
+	for _, addr := range s.SortedValues {
+		snap.Values = append(snap.Values, newJSONLogRecord(s.Values[addr]))
+	}
+	return snap
+}
+
+// saveSnapshot writes the last values of every group address to config.Snapshot.
+// The new file is written and synced under a temporary name before replacing the old one,
+// which is kept as config.Snapshot+".1" (and so on, up to config.SnapshotKeep files).
+func (s *Server) saveSnapshot() error {
+	cfg := getConfig()
+	s.snapshotMutex.Lock()
+	defer s.snapshotMutex.Unlock()
+
+	tmp := cfg.Snapshot + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = func() error {
+		var w io.Writer = file
+		var zw *gzip.Writer
+		if cfg.SnapshotCompress {
+			zw = gzip.NewWriter(file)
+			w = zw
+		}
+		encoder := json.NewEncoder(w)
+		if err := encoder.Encode(s.newSnapshot()); err != nil {
+			return err
+		}
+		if zw != nil {
+			if err := zw.Close(); err != nil {
+				return err
+			}
+		}
+		return file.Sync()
+	}()
+	if err2 := file.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// keep previous snapshots; the current one is hard-linked so that
+	// config.Snapshot always exists
+	for i := cfg.SnapshotKeep; i > 0; i-- {
+		older := fmt.Sprintf("%s.%d", cfg.Snapshot, i)
+		var err error
+		if i > 1 {
+			err = os.Rename(fmt.Sprintf("%s.%d", cfg.Snapshot, i-1), older)
+		} else {
+			os.Remove(older)
+			err = os.Link(cfg.Snapshot, older)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			appLog.Warnf("%v", err)
+		}
+	}
+	if err := os.Rename(tmp, cfg.Snapshot); err != nil {
+		return err
+	}
+	// make the rename durable
+	if dir, err := os.Open(filepath.Dir(cfg.Snapshot)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// snapshotLoop saves a snapshot every config.SnapshotInterval.
+func (s *Server) snapshotLoop() {
+	cfg := getConfig()
+	for {
+		time.Sleep(cfg.SnapshotInterval)
+		appLog.Debugf("Writing status to %s", cfg.Snapshot)
+		if err := s.saveSnapshot(); err != nil {
+			appLog.Errorf("writing %s: %v", cfg.Snapshot, err)
+		}
+	}
+}
+
+// snapshotOnShutdown saves a last snapshot when the program is interrupted or terminated.
+func (s *Server) snapshotOnShutdown() {
+	cfg := getConfig()
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	sig := <-c
+	appLog.Infof("Received %v; writing status to %s", sig, cfg.Snapshot)
+	if err := s.saveSnapshot(); err != nil {
+		appLog.Errorf("writing %s: %v", cfg.Snapshot, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
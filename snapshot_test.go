@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// captureLog sends the application log to a buffer until the end of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	var logs bytes.Buffer
+	out := appLog.out
+	appLog.out = &logs
+	t.Cleanup(func() { appLog.out = out })
+	return &logs
+}
+
+func TestLoadLegacySnapshot(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "status.json.gz")
+	config.Store(&Config{Snapshot: filename})
+	logs := captureLog(t)
+
+	when := time.Date(2026, 7, 1, 12, 30, 45, 0, time.UTC)
+	msg := func(cmd knx.GroupCommand, dest cemi.GroupAddr, data ...byte) knxMsg {
+		return knxMsg{When: when, Where: "gw", Event: knx.GroupEvent{Command: cmd, Source: 0x110A, Destination: dest, Data: data}}
+	}
+	future := msg(knx.GroupWrite, 0x0A04, 1)
+	future.When = time.Now().Add(24 * time.Hour)
+	noTime := msg(knx.GroupWrite, 0x0A05, 1)
+	noTime.When = time.Time{}
+	// version 1 wrote Server.Values as it was
+	legacy := map[cemi.GroupAddr]knxMsg{
+		0x0A01: msg(knx.GroupWrite, 0x0A01, 0, 0x0C, 0x33),
+		0x0A02: msg(knx.GroupResponse, 0x0A02, 1),
+		0x0A03: msg(knx.GroupWrite, 0x0A09, 1), // wrong destination
+		0x0A04: future,
+		0x0A05: noTime,
+		0x0A06: msg(knx.GroupCommand(99), 0x0A06, 1),
+	}
+	b, err := json.Marshal(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(b)
+	zw.Close()
+	if err := os.WriteFile(filename, gz.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{}
+	s.loadSnapshot()
+	if len(s.Values) != 2 || len(s.SortedValues) != 2 || s.SortedValues[0] != 0x0A01 || s.SortedValues[1] != 0x0A02 {
+		t.Fatalf("got values %+v", s.Values)
+	}
+	if k := s.Values[0x0A01]; !k.When.Equal(when) || k.Where != "gw" || k.Event.Source != 0x110A || !bytes.Equal(k.Event.Data, []byte{0, 0x0C, 0x33}) {
+		t.Errorf("got %+v", k)
+	}
+	if k := s.Values[0x0A02]; k.Event.Command != knx.GroupResponse {
+		t.Errorf("got %+v", k)
+	}
+	for _, want := range []string{
+		"migrated 2 values from the legacy format",
+		"dropping " + cemi.GroupAddr(0x0A03).String() + ": destination is " + cemi.GroupAddr(0x0A09).String(),
+		"dropping " + cemi.GroupAddr(0x0A04).String() + ": timestamp ",
+		"dropping " + cemi.GroupAddr(0x0A05).String() + ": no timestamp",
+		"dropping " + cemi.GroupAddr(0x0A06).String() + ": invalid command 99",
+		"2 values loaded, 4 dropped",
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("%q not found in log:\n%s", want, logs)
+		}
+	}
+}
+
+func TestParseSnapshot(t *testing.T) {
+	config.Store(&Config{Snapshot: "status.json"})
+	logs := captureLog(t)
+
+	record := func(when, command, source, dest, data string) string {
+		return `{"Time":"` + when + `","Gateway":"gw","Command":"` + command + `","Source":"` + source +
+			`","Destination":"` + dest + `","Data":"` + data + `"}`
+	}
+	const t1, t2 = "2026-07-01T12:00:00Z", "2026-07-01T13:00:00Z"
+	a1, a2 := cemi.GroupAddr(0x0A01).String(), cemi.GroupAddr(0x0A02).String()
+	values := []string{
+		record(t1, "write", "1.1.10", a1, "01"),
+		record(t2, "write", "1.1.10", a2, "00"),
+		record(t1, "write", "1.1.11", a2, "01"),      // older value for the same address
+		record(t1, "delete", "1.1.10", a1, "01"),     // unknown command
+		record(t1, "write", "1/1/10", a1, "01"),      // invalid source
+		record(t1, "write", "1.1.10", "1.2.3", "01"), // invalid destination
+		record(t1, "write", "1.1.10", a1, "xyz"),     // invalid data
+		record("0001-01-01T00:00:00Z", "write", "1.1.10", a1, "01"),
+	}
+	snap := `{"Version":2,"Saved":"` + t2 + `","Gateways":[{"Address":"gw","Connected":true}],"Values":[` + strings.Join(values, ",") + `]}`
+	got, err := parseSnapshot([]byte(snap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0x0A01].Event.Data[0] != 1 || got[0x0A02].Event.Data[0] != 0 || got[0x0A02].Event.Source != 0x110A {
+		t.Errorf("got %+v", got)
+	}
+	for i, want := range []string{
+		"dropping value 2 (" + a2 + "): duplicate address",
+		"dropping value 3 (" + a1 + "): unknown command \"delete\"",
+		"dropping value 4 (" + a1 + "): ",
+		"dropping value 5 (1.2.3): ",
+		"dropping value 6 (" + a1 + "): ",
+		"dropping value 7 (" + a1 + "): no timestamp",
+		"2 values loaded, 6 dropped",
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("%d: %q not found in log:\n%s", i, want, logs)
+		}
+	}
+
+	for _, bad := range []string{
+		``,
+		`{"Version":2,"Values":[`,
+		`{"Version":3,"Values":[]}`,
+		`{"Version":2,"Values":{}}`,
+		`[1, 2, 3]`,
+	} {
+		if v, err := parseSnapshot([]byte(bad)); err == nil {
+			t.Errorf("%q: no error, got %+v", bad, v)
+		}
+	}
+}
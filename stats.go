@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+const (
+	StatsMaxWindow      = 24 * time.Hour
+	StatsBucketSize     = time.Minute     // longer windows are computed from counters of every minute
+	StatsRepeatInterval = 2 * time.Second // identical telegrams closer than this are counted as repeats
+	StatsTopSize        = 10              // Number of entries in "busiest" lists
+)
+
+// StatsWindows are the rolling windows reported by Stats.Report.
+var StatsWindows = []struct {
+	Name     string
+	Duration time.Duration
+}{
+	{"1m", time.Minute},
+	{"1h", time.Hour},
+	{"24h", 24 * time.Hour},
+}
+
+type statsEntry struct {
+	When        time.Time
+	Where       string
+	Command     knx.GroupCommand
+	Source      cemi.IndividualAddr
+	Destination cemi.GroupAddr
+	Repeat      bool
+}
+
+// statsCounts are the counters of a set of telegrams.
+type statsCounts struct {
+	Telegrams int
+	Reads     int
+	Responses int
+	Writes    int
+	Repeats   int
+	Gateways  map[string]int
+	Lines     map[string]int
+	Groups    map[cemi.GroupAddr]int
+	Sources   map[cemi.IndividualAddr]int
+}
+
+func newStatsCounts() statsCounts {
+	return statsCounts{
+		Gateways: make(map[string]int),
+		Lines:    make(map[string]int),
+		Groups:   make(map[cemi.GroupAddr]int),
+		Sources:  make(map[cemi.IndividualAddr]int),
+	}
+}
+
+func (c *statsCounts) add(e statsEntry) {
+	c.Telegrams++
+	switch e.Command {
+	case knx.GroupRead:
+		c.Reads++
+	case knx.GroupResponse:
+		c.Responses++
+	case knx.GroupWrite:
+		c.Writes++
+	}
+	if e.Repeat {
+		c.Repeats++
+	}
+	c.Gateways[e.Where]++
+	c.Lines[lineName(e.Source)]++
+	c.Groups[e.Destination]++
+	c.Sources[e.Source]++
+}
+
+func (c *statsCounts) merge(o statsCounts) {
+	c.Telegrams += o.Telegrams
+	c.Reads += o.Reads
+	c.Responses += o.Responses
+	c.Writes += o.Writes
+	c.Repeats += o.Repeats
+	for k, n := range o.Gateways {
+		c.Gateways[k] += n
+	}
+	for k, n := range o.Lines {
+		c.Lines[k] += n
+	}
+	for k, n := range o.Groups {
+		c.Groups[k] += n
+	}
+	for k, n := range o.Sources {
+		c.Sources[k] += n
+	}
+}
+
+// statsBucket has the counters of the telegrams seen in one minute.
+type statsBucket struct {
+	Minute time.Time
+	Counts statsCounts
+}
+
+// Stats keeps track of the telegrams seen in the last StatsMaxWindow
+// to compute bus load and traffic statistics.  Telegrams are kept for
+// the last StatsBucketSize only; longer windows add up the counters of every
+// minute, so they may include up to one minute more than their duration,
+// which is taken into account in the rates.
+type Stats struct {
+	mutex   sync.Mutex
+	recent  []statsEntry  // telegrams of the last StatsBucketSize
+	buckets []statsBucket // counters of every minute of the last StatsMaxWindow, oldest first
+	last    map[cemi.GroupAddr]knxMsg
+}
+
+// StatsCount is the number of telegrams (and rate per minute) for a gateway, line, address or device.
+type StatsCount struct {
+	Name  string
+	Count int
+	Rate  float64 // telegrams per minute
+}
+
+// StatsWindow contains the statistics for one rolling window.
+type StatsWindow struct {
+	Window     string
+	Telegrams  int
+	Rate       float64 // telegrams per minute
+	Reads      int
+	Responses  int
+	Writes     int
+	Repeats    int
+	Gateways   []StatsCount
+	Lines      []StatsCount
+	TopGroups  []StatsCount
+	TopSources []StatsCount
+}
+
+// Add records a new telegram.
+func (st *Stats) Add(k knxMsg) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	if st.last == nil {
+		st.last = make(map[cemi.GroupAddr]knxMsg)
+	}
+	e := statsEntry{
+		When:        k.When,
+		Where:       k.Where,
+		Command:     k.Event.Command,
+		Source:      k.Event.Source,
+		Destination: k.Event.Destination,
+	}
+	if prev, ok := st.last[k.Event.Destination]; ok && isRepeat(prev, k) {
+		e.Repeat = true
+	}
+	st.last[k.Event.Destination] = k
+	st.recent = append(st.recent, e)
+	st.bucket(k.When.Truncate(StatsBucketSize)).add(e)
+
+	// forget what is older than the windows
+	limit := k.When.Add(-StatsBucketSize)
+	if len(st.recent) > 0 && st.recent[0].When.Before(limit) {
+		i := sort.Search(len(st.recent), func(i int) bool { return !st.recent[i].When.Before(limit) })
+		st.recent = st.recent[i:]
+	}
+	limit = k.When.Add(-StatsMaxWindow).Truncate(StatsBucketSize)
+	if len(st.buckets) > 0 && st.buckets[0].Minute.Before(limit) {
+		i := sort.Search(len(st.buckets), func(i int) bool { return !st.buckets[i].Minute.Before(limit) })
+		st.buckets = st.buckets[i:]
+	}
+}
+
+// bucket returns the counters of the minute starting at m, creating them if needed.
+func (st *Stats) bucket(m time.Time) *statsCounts {
+	i := sort.Search(len(st.buckets), func(i int) bool { return !st.buckets[i].Minute.Before(m) })
+	if i == len(st.buckets) || !st.buckets[i].Minute.Equal(m) {
+		st.buckets = append(st.buckets, statsBucket{})
+		copy(st.buckets[i+1:], st.buckets[i:])
+		st.buckets[i] = statsBucket{Minute: m, Counts: newStatsCounts()}
+	}
+	return &st.buckets[i].Counts
+}
+
+// isRepeat returns true if k is the same telegram as prev, sent again shortly after it.
+func isRepeat(prev, k knxMsg) bool {
+	if prev.Event.Command != k.Event.Command || prev.Event.Source != k.Event.Source {
+		return false
+	}
+	if k.When.Sub(prev.When) > StatsRepeatInterval {
+		return false
+	}
+	if len(prev.Event.Data) != len(k.Event.Data) {
+		return false
+	}
+	for i := range k.Event.Data {
+		if prev.Event.Data[i] != k.Event.Data[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Report computes the statistics for every window in StatsWindows.
+func (st *Stats) Report() []StatsWindow {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	now := time.Now()
+	var result []StatsWindow
+	for _, w := range StatsWindows {
+		limit := now.Add(-w.Duration)
+		c := newStatsCounts()
+		covered := w.Duration // time the counters are for, to compute the rates
+		if w.Duration <= StatsBucketSize {
+			for _, e := range st.recent {
+				if !e.When.Before(limit) {
+					c.add(e)
+				}
+			}
+		} else {
+			for _, b := range st.buckets {
+				if b.Minute.Add(StatsBucketSize).After(limit) {
+					c.merge(b.Counts)
+					if d := now.Sub(b.Minute); d > covered {
+						// the oldest minute started before the window
+						covered = d
+					}
+				}
+			}
+		}
+		result = append(result, statsWindow(w.Name, covered, c))
+	}
+	return result
+}
+
+// statsWindow returns the statistics of a window, from the counters of the telegrams
+// seen in the last d.
+func statsWindow(name string, d time.Duration, c statsCounts) StatsWindow {
+	cfg := getConfig()
+	minutes := d.Minutes()
+	sw := StatsWindow{
+		Window:    name,
+		Telegrams: c.Telegrams,
+		Rate:      float64(c.Telegrams) / minutes,
+		Reads:     c.Reads,
+		Responses: c.Responses,
+		Writes:    c.Writes,
+		Repeats:   c.Repeats,
+	}
+	for gw, n := range c.Gateways {
+		sw.Gateways = append(sw.Gateways, StatsCount{Name: gw, Count: n, Rate: float64(n) / minutes})
+	}
+	for line, n := range c.Lines {
+		sw.Lines = append(sw.Lines, StatsCount{Name: line, Count: n, Rate: float64(n) / minutes})
+	}
+	for addr, n := range c.Groups {
+		name := addr.String()
+		if nt, ok := cfg.Addresses[addr]; ok {
+			name += " " + nt.Name
+		}
+		sw.TopGroups = append(sw.TopGroups, StatsCount{Name: name, Count: n, Rate: float64(n) / minutes})
+	}
+	for addr, n := range c.Sources {
+		name := addr.String()
+		if dev, ok := cfg.Devices[addr]; ok {
+			name += " " + dev
+		}
+		sw.TopSources = append(sw.TopSources, StatsCount{Name: name, Count: n, Rate: float64(n) / minutes})
+	}
+	sortStatsCounts(sw.Gateways, 0)
+	sortStatsCounts(sw.Lines, 0)
+	sw.TopGroups = sortStatsCounts(sw.TopGroups, StatsTopSize)
+	sw.TopSources = sortStatsCounts(sw.TopSources, StatsTopSize)
+	return sw
+}
+
+// sortStatsCounts sorts c from busiest to quietest, and truncates it to max entries (if max > 0).
+func sortStatsCounts(c []StatsCount, max int) []StatsCount {
+	sort.Slice(c, func(i, j int) bool {
+		if c[i].Count != c[j].Count {
+			return c[i].Count > c[j].Count
+		}
+		return c[i].Name < c[j].Name
+	})
+	if max > 0 && len(c) > max {
+		c = c[:max]
+	}
+	return c
+}
+
+// lineName returns the area and line of an individual address, as in "1.1".
+func lineName(addr cemi.IndividualAddr) string {
+	return fmt.Sprintf("%d.%d", uint8(addr>>12)&0xF, uint8(addr>>8)&0xF)
+}
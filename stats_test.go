@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx"
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+func TestStats(t *testing.T) {
+	config.Store(&Config{})
+	var st Stats
+	now := time.Now()
+	add := func(ago time.Duration, cmd knx.GroupCommand, dest cemi.GroupAddr, data ...byte) {
+		st.Add(knxMsg{When: now.Add(-ago), Where: "gw", Event: knx.GroupEvent{Command: cmd, Source: 0x1101, Destination: dest, Data: data}})
+	}
+	add(30*time.Hour, knx.GroupWrite, 1, 1) // too old for every window
+	add(3*time.Hour, knx.GroupWrite, 1, 1)
+	add(3*time.Hour, knx.GroupRead, 2)
+	add(10*time.Minute, knx.GroupWrite, 2, 1)
+	add(10*time.Second, knx.GroupWrite, 1, 0)
+	add(9*time.Second, knx.GroupWrite, 1, 0) // repeat
+	add(0, knx.GroupResponse, 3, 5)
+
+	if len(st.buckets) != 3 && len(st.buckets) != 4 {
+		// the last telegrams may or may not be in the same minute
+		t.Errorf("got %d buckets, want 3 or 4", len(st.buckets))
+	}
+	want := map[string]StatsWindow{
+		"1m":  {Telegrams: 3, Writes: 2, Responses: 1, Repeats: 1},
+		"1h":  {Telegrams: 4, Writes: 3, Responses: 1, Repeats: 1},
+		"24h": {Telegrams: 6, Writes: 4, Reads: 1, Responses: 1, Repeats: 1},
+	}
+	for _, sw := range st.Report() {
+		w := want[sw.Window]
+		if sw.Telegrams != w.Telegrams || sw.Reads != w.Reads || sw.Writes != w.Writes || sw.Responses != w.Responses || sw.Repeats != w.Repeats {
+			t.Errorf("window %s: got %d telegrams (%d reads, %d writes, %d responses, %d repeats), want %+v",
+				sw.Window, sw.Telegrams, sw.Reads, sw.Writes, sw.Responses, sw.Repeats, w)
+		}
+		if len(sw.TopGroups) == 0 || sw.TopGroups[0].Count < 2 {
+			t.Errorf("window %s: got top groups %+v", sw.Window, sw.TopGroups)
+		}
+	}
+}
+
+func TestStatsRate(t *testing.T) {
+	config.Store(&Config{})
+	var st Stats
+	now := time.Now()
+	// the minute of the first telegram started before the last hour
+	for _, when := range []time.Time{now.Truncate(StatsBucketSize).Add(-time.Hour), now} {
+		st.Add(knxMsg{When: when, Where: "gw", Event: knx.GroupEvent{Command: knx.GroupWrite, Source: 0x1101, Destination: 1, Data: []byte{1}}})
+	}
+	for _, sw := range st.Report() {
+		switch sw.Window {
+		case "1h":
+			if sw.Telegrams != 2 || sw.Rate > 2.0/60 || sw.Rate <= 2.0/61 {
+				t.Errorf("1h: got %d telegrams, %v/min; want 2 in 60-61 minutes", sw.Telegrams, sw.Rate)
+			}
+		case "24h":
+			if sw.Telegrams != 2 || sw.Rate != 2.0/(24*60) {
+				t.Errorf("24h: got %d telegrams, %v/min; want 2 in 24 hours", sw.Telegrams, sw.Rate)
+			}
+		}
+	}
+}
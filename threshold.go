@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+const RulesCheckInterval = 5 * time.Second
+
+// Rule is a "rule" line in the config file: an alert which fires when the value
+// of a group address meets a condition for some time.
+type Rule struct {
+	Name       string
+	Target     string         // group address or name, as written in the config file
+	Addr       cemi.GroupAddr // resolved group address
+	Op         string         // one of > >= < <= = !=
+	Value      float64
+	For        time.Duration // condition must hold this long before firing (debounce)
+	Hysteresis float64       // margin the value must cross back before recovering
+	Renotify   time.Duration // send the alert again after this time while firing (0: never)
+	Pos        string        // file and line where it is defined
+}
+
+// ParseRule parses the tokens of a "rule" line:
+//
+//	rule <name> <group-addr-or-name> <op> <value> [for <duration>] [hysteresis <x>] [renotify <duration>]
+func ParseRule(tokens []string) (Rule, error) {
+	var r Rule
+	if len(tokens) < 5 || len(tokens)%2 == 0 {
+		return r, fmt.Errorf("syntax: rule <name> <address> <op> <value> [for <duration>] [hysteresis <x>] [renotify <duration>]")
+	}
+	r.Name, r.Target, r.Op = tokens[1], tokens[2], tokens[3]
+	switch r.Op {
+	case ">", ">=", "<", "<=", "=", "!=":
+	default:
+		return r, fmt.Errorf("rule %s: invalid operator %q", r.Name, r.Op)
+	}
+	var err error
+	r.Value, err = parseRuleValue(tokens[4])
+	if err != nil {
+		return r, fmt.Errorf("rule %s: %w", r.Name, err)
+	}
+	for i := 5; i < len(tokens); i += 2 {
+		switch tokens[i] {
+		case "for":
+			r.For, err = time.ParseDuration(tokens[i+1])
+		case "hysteresis":
+			r.Hysteresis, err = strconv.ParseFloat(tokens[i+1], 64)
+		case "renotify":
+			r.Renotify, err = time.ParseDuration(tokens[i+1])
+		default:
+			err = fmt.Errorf("unknown option %q", tokens[i])
+		}
+		if err != nil {
+			return r, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+	}
+	return r, nil
+}
+
+func parseRuleValue(s string) (float64, error) {
+	switch strings.ToLower(s) {
+	case "true", "on":
+		return 1, nil
+	case "false", "off":
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// Match returns true if the condition of the rule is met by value v.
+// If the rule is already firing, the value must cross the hysteresis margin to stop matching.
+func (r Rule) Match(v float64, firing bool) bool {
+	h := 0.0
+	if firing {
+		h = r.Hysteresis
+	}
+	switch r.Op {
+	case ">":
+		return v > r.Value-h
+	case ">=":
+		return v >= r.Value-h
+	case "<":
+		return v < r.Value+h
+	case "<=":
+		return v <= r.Value+h
+	case "=":
+		return v == r.Value
+	case "!=":
+		return v != r.Value
+	}
+	return false
+}
+
+func (r Rule) String() string {
+	str := fmt.Sprintf("%s %s %v", r.Target, r.Op, r.Value)
+	if r.For > 0 {
+		str += fmt.Sprintf(" for %s", r.For)
+	}
+	return str
+}
+
+type ruleState struct {
+	Rule
+	matching bool      // the condition is currently met
+	since    time.Time // when the condition started to be met
+	firing   bool      // an alert has been sent and not recovered yet
+	notified time.Time // last time an alert was sent
+	value    float64   // last value
+}
+
+// Rules evaluates the rules in the config file.
+type Rules struct {
+	mutex  sync.Mutex
+	states []*ruleState
+}
+
+// Init creates the state of every rule in the config file.  When the config
+// is reloaded, rules which have not changed keep their state, so that alerts
+// which are firing recover normally.
+func (rs *Rules) Init(rules []Rule) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	old := make(map[Rule]*ruleState)
+	for _, st := range rs.states {
+		r := st.Rule
+		r.Pos = ""
+		old[r] = st
+	}
+	rs.states = nil
+	for _, r := range rules {
+		key := r
+		key.Pos = ""
+		st, ok := old[key]
+		if !ok {
+			st = &ruleState{}
+		}
+		delete(old, key)
+		st.Rule = r
+		rs.states = append(rs.states, st)
+	}
+}
+
+// decodeValue returns the value of a telegram as a float64, using the DPT in the config file.
+func decodeValue(k knxMsg) (float64, error) {
+	dp, err := decodeMsg(k)
+	if err != nil {
+		return 0, err
+	}
+	return GetDPT(dp)
+}
+
+// updateRules evaluates the rules affected by a new telegram.
+func (s *Server) updateRules(k knxMsg) {
+	if len(k.Event.Data) == 0 {
+		// GroupRead: no value
+		return
+	}
+	s.Rules.mutex.Lock()
+	defer s.Rules.mutex.Unlock()
+	for _, st := range s.Rules.states {
+		if st.Addr != k.Event.Destination {
+			continue
+		}
+		v, err := decodeValue(k)
+		if err != nil {
+			appLog.Debugf("rule %s: %v", st.Name, err)
+			continue
+		}
+		st.value = v
+		match := st.Match(v, st.firing)
+		if match && !st.matching {
+			st.since = k.When
+		}
+		st.matching = match
+		s.evalRule(st, k.When)
+	}
+}
+
+// checkRules periodically evaluates the rules, to fire the ones whose condition
+// has been met for long enough and to send reminders.
+func (s *Server) checkRules() {
+	for {
+		time.Sleep(RulesCheckInterval)
+		s.Rules.mutex.Lock()
+		for _, st := range s.Rules.states {
+			s.evalRule(st, time.Now())
+		}
+		s.Rules.mutex.Unlock()
+	}
+}
+
+// evalRule sends an alert if a rule starts firing, recovers, or must be notified again.
+// It must be called with s.Rules.mutex held.
+func (s *Server) evalRule(st *ruleState, now time.Time) {
+	a := Alert{Time: now, Address: st.Addr.String(), Name: getConfig().Addresses[st.Addr].Name}
+	switch {
+	case st.matching && !st.firing && now.Sub(st.since) >= st.For:
+		st.firing = true
+		a.Kind = "threshold"
+		a.Message = fmt.Sprintf("%s: %s (value %v)", st.Name, st.Rule, st.value)
+	case st.matching && st.firing && st.Renotify > 0 && now.Sub(st.notified) >= st.Renotify:
+		a.Kind = "threshold"
+		a.Message = fmt.Sprintf("%s: still %s (value %v)", st.Name, st.Rule, st.value)
+	case !st.matching && st.firing:
+		st.firing = false
+		a.Kind = "recovered"
+		a.Message = fmt.Sprintf("%s: recovered (value %v)", st.Name, st.value)
+	default:
+		return
+	}
+	st.notified = now
+	s.Alert(a)
+}
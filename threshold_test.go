@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestRulesInit(t *testing.T) {
+	hot := Rule{Name: "hot", Target: "serverroom/temperature", Addr: 0x0A01, Op: ">", Value: 28, Pos: "knxweb.conf:10"}
+	cold := Rule{Name: "cold", Target: "serverroom/temperature", Addr: 0x0A01, Op: "<", Value: 15, Pos: "knxweb.conf:11"}
+	var rs Rules
+	rs.Init([]Rule{hot, cold})
+	rs.states[0].firing = true
+	rs.states[1].firing = true
+
+	// reloaded: "hot" has moved to another line, "cold" has changed
+	hot.Pos = "knxweb.conf:12"
+	cold.Value = 10
+	rs.Init([]Rule{cold, hot, hot})
+	if len(rs.states) != 3 {
+		t.Fatalf("got %d rules, want 3", len(rs.states))
+	}
+	if st := rs.states[0]; st.firing || st.Value != 10 {
+		t.Errorf("changed rule: got %+v, want a new state", st)
+	}
+	if st := rs.states[1]; !st.firing || st.Pos != hot.Pos {
+		t.Errorf("unchanged rule: got %+v, want its old state", st)
+	}
+	if st := rs.states[2]; st.firing || st == rs.states[1] {
+		t.Errorf("repeated rule: got %+v, want a new state", st)
+	}
+}
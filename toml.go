@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file contains a parser for the subset of TOML used in config files:
+// key/value pairs with strings, integers, floats, booleans and arrays of them,
+// tables ("[name]") and arrays of tables ("[[name]]").  Dotted keys, inline
+// tables and dates are not supported.
+
+// tomlValue is a value in a TOML document: a string, int64, float64, bool or []interface{}.
+type tomlValue struct {
+	Line  int
+	Value interface{}
+}
+
+// tomlTable is a set of key/value pairs.
+type tomlTable struct {
+	Name   string
+	Line   int
+	Keys   map[string]tomlValue
+	Order  []string // keys in the order they were defined
+	parent *tomlDocument
+}
+
+// tomlDocument is a parsed TOML file.
+type tomlDocument struct {
+	Filename string
+	Root     *tomlTable
+	Tables   []*tomlTable // every "[name]" and "[[name]]", in order
+}
+
+// parseTOML reads a TOML file.  It returns all the syntax errors found.
+func parseTOML(filename string) (*tomlDocument, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	doc := &tomlDocument{Filename: filename}
+	doc.Root = &tomlTable{Keys: make(map[string]tomlValue), parent: doc}
+	current := doc.Root
+	var errs ConfigErrors
+	s := bufio.NewScanner(f)
+	lineNum := 0
+	for s.Scan() {
+		lineNum++
+		start := lineNum
+		line := strings.TrimSpace(stripTOMLComment(s.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && !strings.Contains(line, "=") {
+			// "[name]" and "[[name]]" are the same: every table can be repeated
+			brackets := 1
+			if strings.HasPrefix(line, "[[") {
+				brackets = 2
+			}
+			name := ""
+			if len(line) > 2*brackets && strings.HasSuffix(line, strings.Repeat("]", brackets)) {
+				name = strings.TrimSpace(line[brackets : len(line)-brackets])
+			}
+			if name == "" || strings.ContainsAny(name, "[]. \t") {
+				errs = append(errs, fmt.Errorf("%s line %d: invalid table header %s", filename, lineNum, line))
+				continue
+			}
+			current = &tomlTable{Name: name, Line: lineNum, Keys: make(map[string]tomlValue), parent: doc}
+			doc.Tables = append(doc.Tables, current)
+			continue
+		}
+		i := strings.IndexByte(line, '=')
+		if i < 0 {
+			errs = append(errs, fmt.Errorf("%s line %d: expected key = value", filename, lineNum))
+			continue
+		}
+		key := strings.TrimSpace(line[:i])
+		if key == "" || strings.ContainsAny(key, ". \t\"'") {
+			errs = append(errs, fmt.Errorf("%s line %d: invalid key %q", filename, lineNum, key))
+			continue
+		}
+		text := strings.TrimSpace(line[i+1:])
+		// arrays may span several lines
+		for strings.HasPrefix(text, "[") && !tomlBalanced(text) && s.Scan() {
+			lineNum++
+			text += " " + strings.TrimSpace(stripTOMLComment(s.Text()))
+		}
+		if strings.HasPrefix(text, "[") && !tomlBalanced(text) {
+			errs = append(errs, fmt.Errorf("%s line %d: unterminated array", filename, start))
+			continue
+		}
+		v, rest, err := parseTOMLValue(text)
+		if err == nil && strings.TrimSpace(rest) != "" {
+			err = fmt.Errorf("unexpected %q after value", strings.TrimSpace(rest))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s line %d: %v", filename, start, err))
+			continue
+		}
+		if _, ok := current.Keys[key]; ok {
+			errs = append(errs, fmt.Errorf("%s line %d: duplicate key %q", filename, start, key))
+			continue
+		}
+		current.Keys[key] = tomlValue{Line: start, Value: v}
+		current.Order = append(current.Order, key)
+	}
+	if err := s.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return doc, errs
+	}
+	return doc, nil
+}
+
+// stripTOMLComment removes a comment, if it is not inside a string.
+func stripTOMLComment(line string) string {
+	var quote rune
+	escaped := false
+	for i, c := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && c == '\\':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// tomlBalanced returns true if all the brackets outside strings are closed.
+func tomlBalanced(text string) bool {
+	depth := 0
+	var quote rune
+	escaped := false
+	for _, c := range text {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && c == '\\':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// parseTOMLValue parses a value at the beginning of text, and returns the rest of text.
+func parseTOMLValue(text string) (interface{}, string, error) {
+	text = strings.TrimLeft(text, " \t")
+	if text == "" {
+		return nil, "", fmt.Errorf("missing value")
+	}
+	switch text[0] {
+	case '"':
+		return parseTOMLString(text)
+	case '\'':
+		i := strings.IndexByte(text[1:], '\'')
+		if i < 0 {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		return text[1 : i+1], text[i+2:], nil
+	case '[':
+		var arr []interface{}
+		text = strings.TrimLeft(text[1:], " \t")
+		for {
+			if strings.HasPrefix(text, "]") {
+				return arr, text[1:], nil
+			}
+			v, rest, err := parseTOMLValue(text)
+			if err != nil {
+				return nil, "", err
+			}
+			arr = append(arr, v)
+			text = strings.TrimLeft(rest, " \t")
+			if strings.HasPrefix(text, ",") {
+				text = strings.TrimLeft(text[1:], " \t")
+			} else if !strings.HasPrefix(text, "]") {
+				return nil, "", fmt.Errorf("expected , or ] in array")
+			}
+		}
+	}
+	end := strings.IndexAny(text, ",] \t")
+	if end < 0 {
+		end = len(text)
+	}
+	word, rest := text[:end], text[end:]
+	switch word {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	}
+	clean := strings.ReplaceAll(word, "_", "")
+	if i, err := strconv.ParseInt(clean, 0, 64); err == nil {
+		return i, rest, nil
+	}
+	if f, err := strconv.ParseFloat(clean, 64); err == nil {
+		return f, rest, nil
+	}
+	return nil, "", fmt.Errorf("invalid value %q", word)
+}
+
+// parseTOMLString parses a basic string, with escape sequences.
+func parseTOMLString(text string) (interface{}, string, error) {
+	var sb strings.Builder
+	for i := 1; i < len(text); i++ {
+		c := text[i]
+		switch c {
+		case '"':
+			return sb.String(), text[i+1:], nil
+		case '\\':
+			i++
+			if i >= len(text) {
+				return nil, "", fmt.Errorf("unterminated string")
+			}
+			switch text[i] {
+			case '"', '\\':
+				sb.WriteByte(text[i])
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case 'u':
+				if i+4 >= len(text) {
+					return nil, "", fmt.Errorf("invalid escape sequence")
+				}
+				r, err := strconv.ParseUint(text[i+1:i+5], 16, 32)
+				if err != nil || !utf8.ValidRune(rune(r)) {
+					return nil, "", fmt.Errorf("invalid escape sequence")
+				}
+				sb.WriteRune(rune(r))
+				i += 4
+			default:
+				return nil, "", fmt.Errorf("invalid escape sequence \\%c", text[i])
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return nil, "", fmt.Errorf("unterminated string")
+}
+
+// tomlQuote returns a string as a TOML basic string, which parseTOMLString reads back.
+// Unlike strconv.Quote, it only uses the escape sequences of TOML.
+func tomlQuote(str string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range str {
+		switch {
+		case r == '"' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r < 0x20 || r == 0x7F:
+			fmt.Fprintf(&sb, `\u%04X`, r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// errorf returns an error located at the line of a key (or of the table, if key is empty).
+func (t *tomlTable) errorf(key string, format string, args ...interface{}) error {
+	line := t.Line
+	if v, ok := t.Keys[key]; ok {
+		line = v.Line
+	}
+	return fmt.Errorf("%s line %d: %s", t.parent.Filename, line, fmt.Sprintf(format, args...))
+}
+
+// pos returns the location of a key (or of the table, if key is empty).
+func (t *tomlTable) pos(key string) string {
+	line := t.Line
+	if v, ok := t.Keys[key]; ok {
+		line = v.Line
+	}
+	return fmt.Sprintf("%s:%d", t.parent.Filename, line)
+}
+
+// String returns the value of a key as a string.  Numbers and booleans are converted.
+func (t *tomlTable) String(key string) (string, bool) {
+	v, ok := t.Keys[key]
+	if !ok {
+		return "", false
+	}
+	switch x := v.Value.(type) {
+	case string:
+		return x, true
+	case int64, float64, bool:
+		return fmt.Sprint(x), true
+	}
+	return "", false
+}
+
+// Strings returns the value of a key as a list of strings.  A single string is also accepted.
+func (t *tomlTable) Strings(key string) ([]string, bool) {
+	v, ok := t.Keys[key]
+	if !ok {
+		return nil, false
+	}
+	if s, ok := t.String(key); ok {
+		return []string{s}, true
+	}
+	arr, ok := v.Value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	var result []string
+	for _, e := range arr {
+		s, ok := e.(string)
+		if !ok {
+			return nil, false
+		}
+		result = append(result, s)
+	}
+	return result, true
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseTOMLText parses a TOML document from a string, in a file named "test.toml",
+// and returns the errors found, one per line.
+func parseTOMLText(t *testing.T, text string) (*tomlDocument, []string) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "test.toml")
+	if err := os.WriteFile(filename, []byte(text), 0666); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseTOML(filename)
+	if doc != nil {
+		doc.Filename = "test.toml"
+	}
+	if err != nil {
+		return doc, strings.Split(strings.ReplaceAll(err.Error(), filename, "test.toml"), "\n")
+	}
+	return doc, nil
+}
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]interface{}
+	}{
+		{
+			name: "types",
+			input: `s = "text"
+i = 42
+neg = -7
+hex = 0x1F
+big = 1_000_000
+f = 1.5
+yes = true
+no = false
+lit = 'C:\path\to'
+`,
+			want: map[string]interface{}{
+				"s": "text", "i": int64(42), "neg": int64(-7), "hex": int64(31), "big": int64(1000000),
+				"f": 1.5, "yes": true, "no": false, "lit": `C:\path\to`,
+			},
+		},
+		{
+			name: "comments",
+			input: `# a comment
+a = "not # a comment" # a comment
+b = 'not # a comment either'
+c = "quote \" # still in the string"
+d = 1# no space
+`,
+			want: map[string]interface{}{
+				"a": "not # a comment", "b": "not # a comment either", "c": `quote " # still in the string`, "d": int64(1),
+			},
+		},
+		{
+			name:  "escapes",
+			input: `s = "tab\there\nnew line\r \"quoted\" back\\slash \u00e9\u20AC"`,
+			want:  map[string]interface{}{"s": "tab\there\nnew line\r \"quoted\" back\\slash é€"},
+		},
+		{
+			name: "arrays",
+			input: `empty = []
+one = ["x"]
+mixed = [1, "two", 3.0, true]
+nested = [[1, 2], [3]]
+multi = [
+  "x", # first
+  "y, ]",
+
+  "z", # trailing comma
+]
+`,
+			want: map[string]interface{}{
+				"empty":  []interface{}(nil),
+				"one":    []interface{}{"x"},
+				"mixed":  []interface{}{int64(1), "two", 3.0, true},
+				"nested": []interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{int64(3)}},
+				"multi":  []interface{}{"x", "y, ]", "z"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, errs := parseTOMLText(t, tt.input)
+			if errs != nil {
+				t.Fatal(errs)
+			}
+			if len(doc.Root.Keys) != len(tt.want) {
+				t.Errorf("got %d keys, want %d", len(doc.Root.Keys), len(tt.want))
+			}
+			for k, want := range tt.want {
+				if got := doc.Root.Keys[k].Value; !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: got %#v, want %#v", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTOMLTables(t *testing.T) {
+	input := `logdir = "/var/log/knx"
+
+[gateway]
+address = "192.168.1.10:3671"
+
+[[address]]
+address = "1/2/3"
+name = "kitchen/light"
+
+[[address]]
+address = [
+  "1/2/4",
+]
+name = "kitchen/blind"
+`
+	doc, errs := parseTOMLText(t, input)
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	if got := doc.Root.Order; !reflect.DeepEqual(got, []string{"logdir"}) {
+		t.Errorf("root keys: got %q", got)
+	}
+	type table struct {
+		Name  string
+		Line  int
+		Order []string
+		Lines []int
+	}
+	want := []table{
+		{"gateway", 3, []string{"address"}, []int{4}},
+		{"address", 6, []string{"address", "name"}, []int{7, 8}},
+		{"address", 10, []string{"address", "name"}, []int{11, 14}},
+	}
+	var got []table
+	for _, tb := range doc.Tables {
+		var lines []int
+		for _, k := range tb.Order {
+			lines = append(lines, tb.Keys[k].Line)
+		}
+		got = append(got, table{tb.Name, tb.Line, tb.Order, lines})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got tables %+v, want %+v", got, want)
+	}
+	if pos := doc.Tables[2].pos("name"); pos != "test.toml:14" {
+		t.Errorf("pos: got %q", pos)
+	}
+	if err := doc.Tables[2].errorf("", "oops"); err.Error() != "test.toml line 10: oops" {
+		t.Errorf("errorf: got %q", err)
+	}
+
+	// "[name]" is the same as "[[name]]"
+	single, errs := parseTOMLText(t, strings.NewReplacer("[[", "[", "]]", "]").Replace(input))
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	if len(single.Tables) != len(doc.Tables) {
+		t.Fatalf("[name]: got %d tables, want %d", len(single.Tables), len(doc.Tables))
+	}
+	for i := range doc.Tables {
+		a, b := single.Tables[i], doc.Tables[i]
+		if a.Name != b.Name || a.Line != b.Line || !reflect.DeepEqual(a.Keys, b.Keys) {
+			t.Errorf("[name]: table %d: got %+v, want %+v", i, a, b)
+		}
+	}
+}
+
+func TestTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"no value", "key", []string{`test.toml line 1: expected key = value`}},
+		{"dotted table", "[a.b]", []string{`test.toml line 1: invalid table header [a.b]`}},
+		{"empty table", "\n[]", []string{`test.toml line 2: invalid table header []`}},
+		{"unbalanced table", "[[address]", []string{`test.toml line 1: invalid table header [[address]`}},
+		{"unbalanced table 2", "[address]]", []string{`test.toml line 1: invalid table header [address]]`}},
+		{"dotted key", "a.b = 1", []string{`test.toml line 1: invalid key "a.b"`}},
+		{"quoted key", `"a" = 1`, []string{`test.toml line 1: invalid key "\"a\""`}},
+		{"empty key", "= 1", []string{`test.toml line 1: invalid key ""`}},
+		{"missing value", "a =   # nothing", []string{`test.toml line 1: missing value`}},
+		{"unterminated string", `a = "abc`, []string{`test.toml line 1: unterminated string`}},
+		{"unterminated escape", `a = "abc\`, []string{`test.toml line 1: unterminated string`}},
+		{"unterminated literal string", `a = 'abc`, []string{`test.toml line 1: unterminated string`}},
+		{"invalid escape", `a = "\x41"`, []string{`test.toml line 1: invalid escape sequence \x`}},
+		{"short unicode escape", `a = "\u12"`, []string{`test.toml line 1: invalid escape sequence`}},
+		{"surrogate unicode escape", `a = "\uD800"`, []string{`test.toml line 1: invalid escape sequence`}},
+		{"invalid value", "a = nope", []string{`test.toml line 1: invalid value "nope"`}},
+		{"two values", "a = 1 2", []string{`test.toml line 1: unexpected "2" after value`}},
+		{"array without comma", "a = [1 2]", []string{`test.toml line 1: expected , or ] in array`}},
+		{"unterminated array", "a = [1,\n2", []string{`test.toml line 1: unterminated array`}},
+		{"duplicate key", "a = 1\nb = 2\na = 3", []string{`test.toml line 3: duplicate key "a"`}},
+		{"duplicate key in table", "a = 1\n[gateway]\na = 1\na = 2", []string{`test.toml line 4: duplicate key "a"`}},
+		{"error in multi-line array", "\n\na = [\n  1,\n  x,\n]", []string{`test.toml line 3: invalid value "x"`}},
+		{"error after multi-line array", "a = [\n  1,\n  2,\n]\nb", []string{`test.toml line 5: expected key = value`}},
+		{
+			"several errors",
+			"a = 1\nbad\n\nb = \"x\nc = 2\n[x.y]\nc = 3",
+			[]string{
+				`test.toml line 2: expected key = value`,
+				`test.toml line 4: unterminated string`,
+				`test.toml line 6: invalid table header [x.y]`,
+				`test.toml line 7: duplicate key "c"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := parseTOMLText(t, tt.input)
+			if !reflect.DeepEqual(errs, tt.want) {
+				t.Errorf("got errors %q, want %q", errs, tt.want)
+			}
+		})
+	}
+}
+
+func TestTOMLQuote(t *testing.T) {
+	tests := []struct {
+		str, want string
+	}{
+		{"plain", `"plain"`},
+		{`say "hi" \o/`, `"say \"hi\" \\o/"`},
+		{"a\tb\r\nc", `"a\tb\r\nc"`},
+		{"bell\a esc\x1b del\x7f", `"bell\u0007 esc\u001B del\u007F"`},
+		{"Küche € 💡", `"Küche € 💡"`},
+	}
+	for _, tt := range tests {
+		got := tomlQuote(tt.str)
+		if got != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.str, got, tt.want)
+		}
+		v, rest, err := parseTOMLString(got)
+		if err != nil || rest != "" || v != tt.str {
+			t.Errorf("%q: read back as %q, %q, %v", tt.str, v, rest, err)
+		}
+	}
+}
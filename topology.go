@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// TopologyDevice is a device in the topology, with its live status.
+type TopologyDevice struct {
+	Address    string
+	Name       string
+	InProject  bool       // in the ETS project
+	InConfig   bool       // in the config file
+	LastSeen   *time.Time `json:",omitempty"`
+	SendsTo    []string   `json:",omitempty"` // group addresses it has sent telegrams to
+	Gateways   []string   `json:",omitempty"` // gateways it has been seen on
+	Unexpected string     `json:",omitempty"` // why it should not be on the bus, or not on the gateways it was seen on
+}
+
+// TopologyLine is a line with its devices.
+type TopologyLine struct {
+	Address string // as in "1.1"
+	Name    string `json:",omitempty"`
+	Devices []TopologyDevice
+}
+
+// TopologyArea is an area with its lines.
+type TopologyArea struct {
+	Address string
+	Name    string `json:",omitempty"`
+	Lines   []TopologyLine
+}
+
+// Topology returns the devices of the ETS project, the config file and the bus,
+// as a tree of areas and lines.
+func (s *Server) Topology() []TopologyArea {
+	cfg := getConfig()
+	devices := make(map[cemi.IndividualAddr]*TopologyDevice)
+	areaNames := make(map[string]string)
+	lineNames := make(map[string]string)
+	projectLines := make(map[string]bool)
+	get := func(addr cemi.IndividualAddr) *TopologyDevice {
+		d, ok := devices[addr]
+		if !ok {
+			d = &TopologyDevice{Address: addr.String()}
+			devices[addr] = d
+		}
+		return d
+	}
+
+	if cfg.ETS != nil {
+		for _, pd := range cfg.ETS.Devices {
+			d := get(pd.Address)
+			d.Name = pd.Name
+			d.InProject = true
+			areaNames[areaName(pd.Address)] = pd.Area
+			lineNames[lineName(pd.Address)] = pd.Line
+			projectLines[lineName(pd.Address)] = true
+		}
+	}
+	for addr, name := range cfg.Devices {
+		d := get(addr)
+		d.Name = name
+		d.InConfig = true
+	}
+
+	s.Mutex.Lock()
+	for addr, when := range s.DevicesSeen {
+		d := get(addr)
+		when := when
+		d.LastSeen = &when
+		var groups []cemi.GroupAddr
+		for g := range s.SentTo[addr] {
+			groups = append(groups, g)
+		}
+		sort.Slice(groups, func(i, j int) bool { return groups[i] < groups[j] })
+		for _, g := range groups {
+			d.SendsTo = append(d.SendsTo, g.String())
+		}
+		var gateways []string
+		for gw := range s.SeenOn[addr] {
+			gateways = append(gateways, gw)
+		}
+		sort.Strings(gateways)
+		d.Gateways = gateways
+		switch {
+		case d.InProject || (cfg.ETS == nil && d.InConfig):
+			d.Unexpected = wrongGateway(cfg, addr, gateways)
+		case cfg.ETS == nil:
+			d.Unexpected = "not in config"
+		case projectLines[lineName(addr)]:
+			d.Unexpected = "not in the project"
+		default:
+			d.Unexpected = fmt.Sprintf("line %s is not in the project", lineName(addr))
+		}
+	}
+	s.Mutex.Unlock()
+
+	var addrs []cemi.IndividualAddr
+	for addr := range devices {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+
+	var areas []TopologyArea
+	for _, addr := range addrs {
+		area, line := areaName(addr), lineName(addr)
+		if len(areas) == 0 || areas[len(areas)-1].Address != area {
+			areas = append(areas, TopologyArea{Address: area, Name: areaNames[area]})
+		}
+		a := &areas[len(areas)-1]
+		if len(a.Lines) == 0 || a.Lines[len(a.Lines)-1].Address != line {
+			a.Lines = append(a.Lines, TopologyLine{Address: line, Name: lineNames[line]})
+		}
+		l := &a.Lines[len(a.Lines)-1]
+		l.Devices = append(l.Devices, *devices[addr])
+	}
+	return areas
+}
+
+// wrongGateway returns why a device should not have been seen on some of the gateways,
+// if they are configured with the lines they are connected to; or "" if it is fine.
+func wrongGateway(cfg *Config, addr cemi.IndividualAddr, gateways []string) string {
+	line := lineName(addr)
+	var wrong []string
+	for _, name := range gateways {
+		for _, gw := range cfg.Gateways {
+			if gw.Address != name || len(gw.Lines) == 0 {
+				continue
+			}
+			ok := false
+			for _, l := range gw.Lines {
+				ok = ok || l == line
+			}
+			if !ok {
+				wrong = append(wrong, fmt.Sprintf("%s (lines %s)", gw.Address, strings.Join(gw.Lines, " ")))
+			}
+		}
+	}
+	if len(wrong) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("line %s seen on gateway %s", line, strings.Join(wrong, ", "))
+}
+
+func areaName(addr cemi.IndividualAddr) string {
+	return fmt.Sprint(uint8(addr>>12) & 0xF)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+func TestTopologyWrongGateway(t *testing.T) {
+	var c Config
+	for _, tokens := range [][]string{
+		{"gateway", "gw1", "1/", "1.1"},
+		{"gateway", "gw2", "2/", "1.2", "1.3"},
+		{"gateway", "gw3"},
+	} {
+		if err := c.directive(tokens, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.directive([]string{"gateway", "gw4", "1.1.1"}, "test"); err == nil {
+		t.Errorf("gateway with an invalid line: no error")
+	}
+	if gw := c.Gateways[1]; len(gw.Groups) != 1 || gw.Groups[0] != "2/" || len(gw.Lines) != 2 || gw.Lines[1] != "1.3" {
+		t.Errorf("got gateway %+v", gw)
+	}
+
+	a, b, d := cemi.IndividualAddr(0x1105), cemi.IndividualAddr(0x1207), cemi.IndividualAddr(0x1401)
+	c.Devices = map[cemi.IndividualAddr]string{a: "a", b: "b", d: "d"}
+	config.Store(&c)
+	now := time.Now()
+	s := &Server{
+		DevicesSeen: map[cemi.IndividualAddr]time.Time{a: now, b: now, d: now},
+		SeenOn: map[cemi.IndividualAddr]map[string]time.Time{
+			a: {"gw1": now, "gw3": now},
+			b: {"gw1": now, "gw2": now},
+			d: {"replay": now},
+		},
+	}
+	want := map[string]string{
+		a.String(): "",
+		b.String(): "line 1.2 seen on gateway gw1 (lines 1.1)",
+		d.String(): "",
+	}
+	n := 0
+	for _, area := range s.Topology() {
+		for _, line := range area.Lines {
+			for _, dev := range line.Devices {
+				n++
+				if dev.Unexpected != want[dev.Address] {
+					t.Errorf("%s: got %q, want %q", dev.Address, dev.Unexpected, want[dev.Address])
+				}
+				if len(dev.Gateways) != 2 && dev.Address != d.String() {
+					t.Errorf("%s: got gateways %q", dev.Address, dev.Gateways)
+				}
+			}
+		}
+	}
+	if n != len(want) {
+		t.Errorf("got %d devices, want %d", n, len(want))
+	}
+}
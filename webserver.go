@@ -3,8 +3,9 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
+	"path"
+	"strconv"
 	"strings"
 
 	"github.com/vapourismo/knx-go/knx"
@@ -19,11 +20,11 @@ func (s *Server) webRoot(w http.ResponseWriter, r *http.Request) {
 func (s *Server) getAddrs(str string) []cemi.GroupAddr {
 	var result []cemi.GroupAddr
 
-	if addr, err := cemi.NewGroupAddrString(str); err != nil {
+	if addr, err := cemi.NewGroupAddrString(str); err == nil {
 		return append(result, addr)
 	}
 
-	for key, val := range config.Addresses {
+	for key, val := range getConfig().Addresses {
 		if str == val.Name {
 			return append(result, key)
 		}
@@ -35,6 +36,7 @@ func (s *Server) getAddrs(str string) []cemi.GroupAddr {
 }
 
 func (s *Server) webGet(w http.ResponseWriter, r *http.Request) {
+	cfg := getConfig()
 	path := r.URL.Path[5:]
 	if path == "latest" {
 		s.Mutex.Lock()
@@ -48,6 +50,9 @@ func (s *Server) webGet(w http.ResponseWriter, r *http.Request) {
 	} else if path == "all" {
 		s.Mutex.Lock()
 		for i := range s.SortedValues {
+			if nt, ok := cfg.Addresses[s.SortedValues[i]]; ok && hasFlag(nt.Flags, "hidden") {
+				continue
+			}
 			fmt.Fprintf(w, "%+v\n", s.Values[s.SortedValues[i]])
 		}
 		s.Mutex.Unlock()
@@ -66,6 +71,15 @@ func (s *Server) webGet(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		s.Mutex.Unlock()
+	} else if strings.HasPrefix(path, "xref/") {
+		addrs := s.getAddrs(path[5:])
+		if len(addrs) == 0 {
+			http.Error(w, "400 Bad Request", http.StatusBadRequest)
+			return
+		}
+		for _, addr := range addrs {
+			s.Xref(addr).WriteText(w)
+		}
 	} else if strings.HasPrefix(path, "raw/") {
 		addrs := s.getAddrs(path[4:])
 		if len(addrs) == 0 {
@@ -76,10 +90,10 @@ func (s *Server) webGet(w http.ResponseWriter, r *http.Request) {
 		s.Mutex.Lock()
 		for _, addr := range addrs {
 			if msg, ok := s.Values[addr]; ok {
-				if nt, ok := config.Addresses[addr]; ok {
+				if nt, ok := cfg.Addresses[addr]; ok {
 					dp, ok := dpt.Produce(nt.DPT)
 					if !ok {
-						fmt.Printf("Warning: unknown type %v in config file\n", nt.DPT)
+						appLog.Warnf("unknown type %v in config file", nt.DPT)
 						dp = new(UnknownDPT)
 					}
 					if err := dp.Unpack(msg.Event.Data); err != nil {
@@ -111,6 +125,7 @@ func (s *Server) webGet(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) webSet(w http.ResponseWriter, r *http.Request) {
+	cfg := getConfig()
 	path := r.URL.Path[5:]
 	parts := strings.Split(path, "/")
 	if len(parts) < 2 {
@@ -122,8 +137,12 @@ func (s *Server) webSet(w http.ResponseWriter, r *http.Request) {
 
 	var groupAddr cemi.GroupAddr
 	var DPT string
-	for key, val := range config.Addresses {
+	for key, val := range cfg.Addresses {
 		if groupName == val.Name {
+			if hasFlag(val.Flags, "readonly") {
+				http.Error(w, "403 Forbidden", http.StatusForbidden)
+				return
+			}
 			groupAddr = key
 			DPT = val.DPT
 			break
@@ -135,11 +154,11 @@ func (s *Server) webSet(w http.ResponseWriter, r *http.Request) {
 	s.Mutex.Lock()
 	msg, ok := s.Values[groupAddr]
 	s.Mutex.Unlock()
-	if ok && msg.Where != "" {
+	if ok && msg.Where != "" && msg.Where != ReplayGateway {
 		where = msg.Where
 	} else {
 		groupName := groupAddr.String()
-		for _, gw := range config.Gateways {
+		for _, gw := range cfg.Gateways {
 			for _, g := range gw.Groups {
 				if strings.HasPrefix(groupName, g) {
 					where = gw.Address
@@ -157,7 +176,7 @@ func (s *Server) webSet(w http.ResponseWriter, r *http.Request) {
 	}
 	dp, ok := dpt.Produce(DPT)
 	if !ok {
-		fmt.Printf("Warning: unknown type %v in config file\n", DPT)
+		appLog.Warnf("unknown type %v in config file", DPT)
 		http.Error(w, "406 Not Acceptable", http.StatusNotAcceptable)
 		return
 	}
@@ -173,10 +192,8 @@ func (s *Server) webSet(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	if s.Debug {
-		log.Printf("client = %v", client)
-		log.Printf("Writing to %s: %v=%v", where, groupAddr, dp)
-	}
+	appLog.Debugf("client = %v", client)
+	appLog.Debugf("Writing to %s: %v=%v", where, groupAddr, dp)
 	event := knx.GroupEvent{
 		Command:     knx.GroupWrite,
 		Destination: groupAddr,
@@ -192,13 +209,213 @@ func (s *Server) webSet(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "SET: %v=%v\n", groupAddr, dp)
 }
 
+func (s *Server) webStats(w http.ResponseWriter, r *http.Request) {
+	for _, sw := range s.Stats.Report() {
+		fmt.Fprintf(w, "Last %s: %d telegrams (%.2f/min), %d reads, %d responses, %d writes, %d repeats\n",
+			sw.Window, sw.Telegrams, sw.Rate, sw.Reads, sw.Responses, sw.Writes, sw.Repeats)
+		for _, list := range []struct {
+			title  string
+			counts []StatsCount
+		}{
+			{"gateway", sw.Gateways},
+			{"line", sw.Lines},
+			{"group address", sw.TopGroups},
+			{"source", sw.TopSources},
+		} {
+			for _, c := range list.counts {
+				fmt.Fprintf(w, "  %s %s: %d (%.2f/min)\n", list.title, c.Name, c.Count, c.Rate)
+			}
+		}
+	}
+}
+
+func (s *Server) apiStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Stats.Report())
+}
+
+func (s *Server) webInventory(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/inventory/config" {
+		for _, line := range s.Inventory.ConfigLines() {
+			fmt.Fprintln(w, line)
+		}
+		return
+	}
+	for _, e := range s.Inventory.Devices() {
+		fmt.Fprintf(w, "device %s: %d telegrams, first seen %s, last seen %s\n",
+			e.Address, e.Count, e.FirstSeen.Format("2006-01-02 15:04:05"), e.LastSeen.Format("2006-01-02 15:04:05"))
+	}
+	for _, e := range s.Inventory.Groups() {
+		fmt.Fprintf(w, "address %s: %d telegrams, first seen %s, last seen %s, DPT %s?\n",
+			e.Address, e.Count, e.FirstSeen.Format("2006-01-02 15:04:05"), e.LastSeen.Format("2006-01-02 15:04:05"), e.DPT)
+	}
+}
+
+func (s *Server) apiInventory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Devices   []InventoryEntry
+		Addresses []InventoryEntry
+		Config    []string
+	}{
+		Devices:   s.Inventory.Devices(),
+		Addresses: s.Inventory.Groups(),
+		Config:    s.Inventory.ConfigLines(),
+	})
+}
+
+func (s *Server) apiInfer(w http.ResponseWriter, r *http.Request) {
+	addr := strings.TrimPrefix(r.URL.Path, "/api/infer")
+	addr = strings.TrimPrefix(addr, "/")
+	var result []DPTInference
+	for _, di := range s.Inventory.Inferences() {
+		if addr == "" || addr == di.Address {
+			result = append(result, di)
+		}
+	}
+	if addr != "" && len(result) == 0 {
+		http.Error(w, "404 Not Found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (s *Server) webLiveness(w http.ResponseWriter, r *http.Request) {
+	for _, ls := range s.Liveness() {
+		state := "ok"
+		if ls.Stale {
+			state = "STALE"
+		}
+		last := "never"
+		if !ls.LastSeen.IsZero() {
+			last = ls.LastSeen.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s %s: %s (last seen %s, expected every %s)\n", ls.Address, ls.Name, state, last, ls.Interval)
+	}
+}
+
+func (s *Server) apiLiveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Liveness())
+}
+
+func (s *Server) apiRooms(w http.ResponseWriter, r *http.Request) {
+	room := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/rooms"), "/")
+	w.Header().Set("Content-Type", "application/json")
+	if room == "" {
+		json.NewEncoder(w).Encode(Rooms())
+		return
+	}
+	rv, ok := s.RoomValues(room)
+	if !ok {
+		http.Error(w, "404 Not Found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(rv)
+}
+
+func (s *Server) apiTopology(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Topology())
+}
+
+func (s *Server) apiXref(w http.ResponseWriter, r *http.Request) {
+	addrs := s.getAddrs(strings.TrimPrefix(r.URL.Path, "/api/xref/"))
+	if len(addrs) == 0 {
+		http.Error(w, "404 Not Found", http.StatusNotFound)
+		return
+	}
+	var result []Xref
+	for _, addr := range addrs {
+		result = append(result, s.Xref(addr))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (s *Server) apiReplay(w http.ResponseWriter, r *http.Request) {
+	var err error
+	if r.URL.Path != "/api/replay" && r.Method != http.MethodPost {
+		http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch r.URL.Path {
+	case "/api/replay":
+	case "/api/replay/start":
+		file := r.FormValue("file")
+		if file == "" || strings.Contains(file, "..") {
+			http.Error(w, "400 Bad Request", http.StatusBadRequest)
+			return
+		}
+		speed := 1.0
+		if sp := r.FormValue("speed"); sp != "" {
+			if speed, err = strconv.ParseFloat(sp, 64); err != nil {
+				http.Error(w, fmt.Sprintf("400 Bad Request: %s", err.Error()), http.StatusBadRequest)
+				return
+			}
+		}
+		step := r.FormValue("step") != ""
+		// Only files in the log directory can be replayed
+		err = s.StartReplay(path.Join(getConfig().Logdir, file), speed, step)
+	case "/api/replay/step":
+		err = s.StepReplay()
+	case "/api/replay/stop":
+		err = s.StopReplay()
+	default:
+		http.Error(w, "404 Not Found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("409 Conflict: %s", err.Error()), http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.ReplayState())
+}
+
 func (s *Server) WebServer() {
+	cfg := getConfig()
 	// URLs:
 	// /get/<group-name>       <- get value of last write to <group-name>
+	// /get/xref/<group-name>  <- devices linked to <group-name>, and devices seen sending to it
 	// /set/<group-name>/value <- write value to <group-name> in the network
+	// /stats                  <- bus load and traffic statistics
+	// /api/stats              <- same, in JSON
+	// /inventory              <- devices and group addresses seen but not in config
+	// /inventory/config       <- suggested config lines for them
+	// /api/inventory          <- same, in JSON
+	// /api/infer[/<addr>]     <- likely DPTs of group addresses not in config
+	// /liveness               <- devices and group addresses with "expect" lines
+	// /api/liveness           <- same, in JSON
+	// /api/replay             <- state of the replay of a recorded log
+	// /api/replay/start       <- start replaying a log (file=..., speed=..., step=1)
+	// /api/replay/step        <- replay next telegram (when replaying step by step)
+	// /api/replay/stop        <- stop replaying
+	// /api/search             <- search archived logs (from, to, addr, source, command, value, limit)
+	// /api/rooms              <- rooms with their devices and group addresses
+	// /api/rooms/<room>       <- last values of the group addresses in <room>
+	// /api/topology           <- areas, lines and devices, with live status
+	// /api/xref/<group-name>  <- same as /get/xref/, in JSON
 	http.HandleFunc("/", s.webRoot)
 	http.HandleFunc("/get/", s.webGet)
 	http.HandleFunc("/set/", s.webSet)
-	log.Printf("Starting web server on port %d...", config.Port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", config.Port), nil))
+	http.HandleFunc("/stats", s.webStats)
+	http.HandleFunc("/api/stats", s.apiStats)
+	http.HandleFunc("/inventory", s.webInventory)
+	http.HandleFunc("/inventory/", s.webInventory)
+	http.HandleFunc("/api/inventory", s.apiInventory)
+	http.HandleFunc("/api/infer", s.apiInfer)
+	http.HandleFunc("/api/infer/", s.apiInfer)
+	http.HandleFunc("/liveness", s.webLiveness)
+	http.HandleFunc("/api/liveness", s.apiLiveness)
+	http.HandleFunc("/api/replay", s.apiReplay)
+	http.HandleFunc("/api/replay/", s.apiReplay)
+	http.HandleFunc("/api/search", s.apiSearch)
+	http.HandleFunc("/api/rooms", s.apiRooms)
+	http.HandleFunc("/api/rooms/", s.apiRooms)
+	http.HandleFunc("/api/topology", s.apiTopology)
+	http.HandleFunc("/api/xref/", s.apiXref)
+	appLog.Infof("Starting web server on port %d...", cfg.Port)
+	appLog.Fatalf("%v", http.ListenAndServe(fmt.Sprintf(":%d", cfg.Port), nil))
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vapourismo/knx-go/knx/cemi"
+)
+
+// XrefObject is a group object linked to a group address in the ETS project.
+type XrefObject struct {
+	Device     string
+	DeviceName string
+	Object     string
+	DPT        string `json:",omitempty"`
+	Flags      string // as in "CRWTUI"
+}
+
+// XrefSender is a device which has sent telegrams to a group address.
+type XrefSender struct {
+	Device     string
+	DeviceName string `json:",omitempty"`
+	LastSeen   time.Time
+	Unexpected bool `json:",omitempty"` // it has written or responded to the group address, with no linked object which can transmit or respond
+}
+
+// Xref is the cross-reference of a group address: who should send and listen, and who sends.
+type Xref struct {
+	Address string
+	Name    string `json:",omitempty"`
+	Objects []XrefObject
+	Senders []XrefSender
+}
+
+// Xref returns the cross-reference of a group address.
+func (s *Server) Xref(addr cemi.GroupAddr) Xref {
+	cfg := getConfig()
+	x := Xref{Address: addr.String(), Name: cfg.Addresses[addr].Name}
+	canSend := make(map[cemi.IndividualAddr]bool)
+	if cfg.ETS != nil {
+		for _, d := range cfg.ETS.Devices {
+			for _, obj := range d.Objects {
+				for _, g := range obj.Groups {
+					if g != addr {
+						continue
+					}
+					x.Objects = append(x.Objects, XrefObject{
+						Device:     d.Address.String(),
+						DeviceName: cfg.Devices[d.Address],
+						Object:     obj.Name,
+						DPT:        obj.DPT,
+						Flags:      obj.Flags,
+					})
+					if strings.ContainsAny(obj.Flags, "TR") {
+						canSend[d.Address] = true
+					}
+				}
+			}
+		}
+	}
+
+	s.Mutex.Lock()
+	for dev, groups := range s.SentTo {
+		if when, ok := groups[addr]; ok {
+			// any object can send a GroupRead
+			_, wrote := s.WroteTo[dev][addr]
+			x.Senders = append(x.Senders, XrefSender{
+				Device:     dev.String(),
+				DeviceName: cfg.Devices[dev],
+				LastSeen:   when,
+				Unexpected: cfg.ETS != nil && wrote && !canSend[dev],
+			})
+		}
+	}
+	s.Mutex.Unlock()
+	sort.Slice(x.Senders, func(i, j int) bool { return x.Senders[i].LastSeen.After(x.Senders[j].LastSeen) })
+	return x
+}
+
+// WriteText writes a cross-reference in the format used by /get/.
+func (x Xref) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "%s %s\n", x.Address, x.Name)
+	for _, o := range x.Objects {
+		fmt.Fprintf(w, "  linked: %s %s: %s (%s) %s\n", o.Device, o.DeviceName, o.Object, o.DPT, o.Flags)
+	}
+	for _, snd := range x.Senders {
+		str := fmt.Sprintf("  sent by: %s %s, last %s", snd.Device, snd.DeviceName, snd.LastSeen.Format("2006-01-02 15:04:05"))
+		if snd.Unexpected {
+			str += " UNEXPECTED"
+		}
+		fmt.Fprintln(w, str)
+	}
+}